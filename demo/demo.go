@@ -35,7 +35,7 @@ func main() {
     //Select a partitioning method
     option    := 0
     stdin     := bufio.NewReader(os.Stdin)
-    methods   := []string{"Centroid", "DataMidPoint", "Geometric Median", "XYZ Medians"}
+    methods   := []string{"Centroid", "DataMidPoint", "Geometric Median", "Regular", "XYZ Medians"}
     fmt.Println("\n>>> Enter a number to select the corresponding partitioning method <<<")
     for k,v := range methods {
         fmt.Printf("(%d) %s", k+1, v)
@@ -50,28 +50,30 @@ func main() {
     }
 
     //Create the octree with the selected method
-    octree.Make(methods[option-1], terminal_N, &points)
+    tree, err := octree.New(octree.Config{ METHOD: methods[option-1], TERMINAL_N: terminal_N, PROGRESS: octree.ProgressBar(os.Stdout) }, &points)
+    if err != nil { log.Fatalln(err) }
     fmt.Print("\n- octree created. ")
     pause()
 
     //Display summary
-    octree.Summarize()
-    //octree.Summarize("demo.txt")
+    if err = tree.Summarize(); err != nil { log.Fatalln(err) }
+    //tree.Summarize("demo.txt")
     fmt.Print("\n- octree summarized. ")
     pause()
 
     //JSON Export
-    octree.Export("demo.json", false)
+    if err = tree.Export("demo.json", false, false); err != nil { log.Fatalln(err) }
     fmt.Print("\n- octree exported. ")
     pause()
 
     //JSON Import
-    octree.Import("demo.json")
+    tree, err = octree.Import("demo.json")
+    if err != nil { log.Fatalln(err) }
     fmt.Print("\n- octree imported. ")
     pause()
 
     //Plot histogram
-    octree.Histogram(plotWidth, plotHeight, pngFile)
+    if err = tree.Histogram(plotWidth, plotHeight, pngFile); err != nil { log.Fatalln(err) }
     fmt.Print("\n- histogram created. ")
     pause()
 
@@ -79,14 +81,16 @@ func main() {
     queries: for queryNo := 1; queryNo <= numQueries; queryNo++ {
         k := "#" + strconv.Itoa(rand.Intn(numPts) + 1)
         v := points[k]
-        for _, identifier := range strings.Split(octree.Query(&v), ",") {
+        keys, err := tree.Query(&v)
+        if err != nil { log.Fatalln(err) }
+        for _, identifier := range keys {
             if k == identifier {
                 fmt.Printf("Query Key = %s, Point = %v -> PASS\n", k, v)
                 continue queries
             }
         }
         fmt.Printf("Query Key = %s, Point = %v -> FAIL\n", k, v)
-        log.Fatalln("\aQuery returned " + octree.Query(&v))
+        log.Fatalln("\aQuery returned " + strings.Join(keys, ","))
     }
     fmt.Println("\nSUCCESS!")
 }
@@ -4,178 +4,796 @@
  *  Overview:
  *      package for creating, reporting, importing, exporting and querying point-region octrees.
  *  Types:
+ *      Config
+ *          Structure for an octree's build configuration: partitioning method, termination criterion, limits, etc.
  *      DataCoords
  *          Array for a data point's R^3 coordinates, i.e., [1.,2.,3.]
  *      DataSet
  *          Map for the R^3 data points keyed on identifiers, i.e., "Pt1":[1.,2.,3.], "Pt2":[4.,5.,6.], etc.
+ *      Error
+ *          Error returned by the package's functions and methods, wrapping one of the Err sentinels or a system error.
+ *      MedianStats
+ *          Structure for the convergence diagnostics of the geometric-median partitioning method.
+ *      Neighbor
+ *          Structure for a nearest-neighbour query result: the data key and its distance to the query point.
+ *      Octree
+ *          Handle for a point-region octree and its meta data. Any number of octrees can be held side by side.
+ *      Progress
+ *          Structure for a build's progress: points placed, nodes created and elapsed time.
  *  Variables:
- *      MaxIterations
- *          Maxinum number of iterations for the geometric-median partition method
- *      Tol
- *          Convergence tolerance for the geometric-median partition method
+ *      ErrBadArgument, ErrBadFile, ErrBadMethod, ErrDuplicateKey, ErrEmptyTree, ErrKeyNotFound, ErrNoCoords, ErrNoPoints,
+ *      ErrNotConverged
+ *          Sentinel errors for use with errors.Is
  *  Functions:
- *      Export(file string, compact bool)
+ *      Convert(inFile, outFile string, compact bool) error
+ *          Converts an exported octree file from the JSON format to the binary one or vice versa.
+ *      Delete(key string) error
+ *          Deletes a data point from the default octree.
+ *      Export(file string, compact, points bool) error
+ *          Exports the default octree and its meta data to a specified file using the JSON format with or without
+ *          newlines and identations, and with or without the point coordinates.
+ *      ExportBinary(file string, points bool) error
+ *          Exports the default octree and its meta data to a specified file using the binary format, with or without
+ *          the point coordinates.
+ *      Histogram(plotWidth, plotHeight int, pngFile string) error
+ *          Plots a histogram of the default octree's leaf point counts and saves it to the specified PNG file.
+ *          The population mean (mu) and standard deviation (sigma) of the leaf counts are also illustrated.
+ *      Import(file string) (*Octree, error)
+ *          Imports an octree and its meta data from a specified JSON or binary file and makes it the default octree.
+ *      InBox(min, max DataCoords) ([]string, error)
+ *          Searches the default octree for the data points lying inside an axis-aligned box.
+ *      Insert(key string, pt DataCoords) error
+ *          Inserts a data point into the default octree.
+ *      KNearest(pt DataCoords, k int) ([]Neighbor, error)
+ *          Searches the default octree for the k data points nearest to the specified query point.
+ *      Make(cfg Config, refPoints *DataSet) (*Octree, error)
+ *          Creates a point-region octree recursively using a specified configuration and makes it the default octree.
+ *      MakeContext(ctx context.Context, cfg Config, refPoints *DataSet) (*Octree, error)
+ *          Creates a point-region octree like Make, the build being stopped should the context be cancelled.
+ *      New(cfg Config, refPoints *DataSet) (*Octree, error)
+ *          Creates a point-region octree recursively using a specified configuration.
+ *      NewContext(ctx context.Context, cfg Config, refPoints *DataSet) (*Octree, error)
+ *          Creates a point-region octree like New, the build being stopped should the context be cancelled.
+ *      ProgressBar(writer io.Writer) func(Progress)
+ *          Returns a progress callback drawing a console progress bar to the specified writer, e.g., os.Stdout.
+ *      Query(refQueryPt *DataCoords) ([]string, error)
+ *          Traverses the default octree top-down to get the data keys at the leaf node corresponding to the octant in
+ *          which lies the specified query point.
+ *      ReadFrom(reader io.Reader) (*Octree, error)
+ *          Imports an octree and its meta data from a JSON stream, one node at a time, and makes it the default octree.
+ *      RegisterMethod(name string, calcCenter func([]DataCoords) DataCoords) error
+ *          Adds a custom partitioning method computing a node's partition point from the coordinates of its data points.
+ *      Summarize(output ...string) error
+ *          Outputs the meta data and various statistics regarding the default octree to a specified file or Stdout.
+ *      WithinRadius(center DataCoords, r float64) ([]string, error)
+ *          Searches the default octree for the data points lying within a given distance of a specified point.
+ *      WriteTo(writer io.Writer) (int64, error)
+ *          Exports the default octree and its meta data to a JSON stream in compact mode, one node at a time.
+ *  Methods:
+ *      (*Octree) Config() Config
+ *          Returns the configuration with which the octree was built, the defaults being filled in.
+ *      (*Octree) Delete(key string) error
+ *          Deletes a data point from the octree, merging subtrees back into leaf nodes as required.
+ *      (*Octree) Export(file string, compact, points bool) error
  *          Exports the octree and its meta data to a specified file using the JSON format with or without newlines
- *          and identations.
- *      Histogram(plotWidth, plotHeight int, pngFile string)
+ *          and identations, and with or without the point coordinates.
+ *      (*Octree) ExportBinary(file string, points bool) error
+ *          Exports the octree and its meta data to a specified file using the binary format, with or without the
+ *          point coordinates.
+ *      (*Octree) Histogram(plotWidth, plotHeight int, pngFile string) error
  *          Plots a histogram of the leaf point counts and saves it to the specified PNG file.
- *          The population mean (mu) and standard deviation (sigma) of the leaf counts are also illustrated.
- *      Import(file string)
- *          Imports an octree and its meta data from a specified JSON file.
- *      Make(method string, terminal_N int, refPoints *DataSet)
- *          Creates a point-region octree recursively using a specified partitioning method and termination criterion.
- *      Query(refQueryPt *DataCoords) string
- *          Traverses an octree top-down to get the data keys at the leaf node corresponding to the octant in
+ *      (*Octree) InBox(min, max DataCoords) ([]string, error)
+ *          Searches the octree for the data points lying inside an axis-aligned box.
+ *      (*Octree) Insert(key string, pt DataCoords) error
+ *          Inserts a data point into the octree, splitting the receiving leaf node as required.
+ *      (*Octree) KNearest(pt DataCoords, k int) ([]Neighbor, error)
+ *          Searches the octree for the k data points nearest to the specified query point.
+ *      (*Octree) MedianStats() MedianStats
+ *          Returns the convergence diagnostics of the geometric-median method of partitioning.
+ *      (*Octree) Points() (DataSet, error)
+ *          Reconstructs the data set from the keys & point coordinates held by the leaf nodes.
+ *      (*Octree) Query(refQueryPt *DataCoords) ([]string, error)
+ *          Traverses the octree top-down to get the data keys at the leaf node corresponding to the octant in
  *          which lies the specified query point.
- *      Summarize(output ...string)
+ *      (*Octree) ReadBinary(reader io.Reader) (int64, error)
+ *          Replaces the octree with one imported from a binary stream.
+ *      (*Octree) ReadFrom(reader io.Reader) (int64, error)
+ *          Replaces the octree with one imported from a JSON stream, one node at a time.
+ *      (*Octree) Summarize(output ...string) error
  *          Outputs the meta data and various statistics regarding the octree to a specified file or Stdout.
+ *      (*Octree) WithinRadius(center DataCoords, r float64) ([]string, error)
+ *          Searches the octree for the data points lying within a given distance of a specified point.
+ *      (*Octree) WriteBinary(writer io.Writer, points bool) (int64, error)
+ *          Exports the octree and its meta data to a binary stream, with or without the point coordinates.
+ *      (*Octree) WriteJSON(writer io.Writer, compact, points bool) (int64, error)
+ *          Exports the octree and its meta data to a JSON stream, one node at a time, with or without newlines and
+ *          indentations, and with or without the point coordinates.
+ *      (*Octree) WriteTo(writer io.Writer) (int64, error)
+ *          Exports the octree and its meta data to a JSON stream in compact mode, one node at a time.
  *  History:
  *      v1.0.0 - October 26, 2016 - Original release.
+ *      v2.0.0 - October 16, 2026 - Octree handle type replacing the package-global octree.
+ *                                  Errors returned instead of halting the process.
+ *                                  Build configuration passed to Make & New instead of the Tol & MaxIterations variables.
+ *                                  Progress reported to an optional callback instead of a bar written to Stdout.
+ *                                  JSON streamed one node at a time by Export & Import.
+ *                                  Versioned little-endian binary format alongside the JSON one.
+ *                                  Point coordinates optionally embedded in exports & restored on import.
  *============================================================================================================================*/
 package octree
 
 import(
     "bitbucket.org/binet/go-gnuplot/pkg/gnuplot"
+    "bufio"
+    "bytes"
+    "container/heap"
+    "context"
+    "encoding/binary"
     "encoding/json"
+    "errors"
     "fmt"
     "github.com/cznic/mathutil"
     "github.com/dustin/go-humanize"
+    "io"
     "io/ioutil"
-    "log"
     "math"
+    "math/bits"
     "os"
     "path/filepath"
     "runtime"
     "sort"
     "strings"
+    "sync"
     "text/template"
     "time"
 )
 //Exported ---------------------------------------------------------------------------------------------------------------------
 type(
+    Config        struct {              //octree build configuration, a zero field selecting its default:
+        METHOD        string         `json:"-"`             // partitioning method
+        TERMINAL_N    int            `json:"-"`             // termination criterion: max no of points in a leaf node (>1)
+        TOL           float64        `json:"tol"`           // convergence tolerance for the geometric-median method: 1.0E-12
+        MAXITERATIONS int            `json:"max_iterations"` // max no of iterations for the geometric-median method: 1,000
+        MAXDEPTH      int            `json:"max_depth"`     // max depth of a node, beyond which it becomes a leaf: no limit
+        MINCELLSIZE   float64        `json:"min_cell_size"` // extent of a node's points at or below which it's a leaf: none
+        PADCUBE       bool           `json:"pad_cube"`      // flag for padding the regular method's root cube to a power of 2
+        SPARSE        bool           `json:"sparse"`        // flag for leaving out empty octants instead of allocating leaves
+        WORKERS       int            `json:"-"`             // max no of goroutines building the octree: number of CPUs
+        PROGRESS      func(Progress) `json:"-"`             // callback reporting the build's progress: none
+    }
     DataCoords    [3]float64            //array for a data point's R^3 coordinates
     DataSet       map[string]DataCoords //map for the R^3 data points keyed on identifiers
+    Error         struct {              //error returned by the package's functions and methods:
+        Op        string                // name of the failing function
+        Msg       string                // description of the failure
+        Err       error                 // underlying sentinel or system error
+    }
+    MedianStats   struct {              //convergence diagnostics of the geometric-median method of partitioning:
+        NODES      int                  // number of nodes partitioned
+        ITERATIONS int                  // total number of Weiszfeld iterations
+        WORST      int                  // largest number of Weiszfeld iterations for a node
+        ACCEPTED   [3]int               // number of Aitken extrapolations accepted per axis
+        REJECTED   [3]int               // number of Aitken extrapolations rejected per axis
+        RESIDUAL   float64              // largest discrepancy between the last two estimates of a node at termination
+        FALLBACKS  int                  // number of nodes partitioned at their XYZ medians for want of a finite estimate
+    }
+    Neighbor      struct {              //result of a nearest-neighbour query:
+        KEY       string                // data-point identifier
+        DISTANCE  float64               // Euclidean distance to the query point
+    }
+    Octree        struct {              //point-region octree:
+        config    Config                // build configuration, the defaults filled in
+        median    medianDiag            // geometric-median diagnostics of the build & insertions
+        nodes     []node                // octree as a slice of nodes
+        stats     statistics            // octree meta data & statistics
+        index     map[string]int        // leaf-node indices keyed on the data-point identifiers, built on demand
+        parents   []int                 // parent-node indices, -1 for the root, built with the index
+        stale     bool                  // flag for statistics outdated by insertions
+    }
+    Progress      struct {              //progress of an octree build:
+        POINTS    int                   // number of data points placed in leaf nodes
+        TOTAL     int                   // total number of data points
+        NODES     int                   // number of nodes created
+        ELAPSED   time.Duration         // time elapsed since the start of the build
+    }
 )
 var(
-    MaxIterations = 1000                //max no of iterations for the geometric-median method of partitioning
-    Tol           = 1.0E-12             //convergence tolerance for the geometric-median method of partitioning
+    ErrBadArgument  = errors.New("invalid argument")                          //sentinel errors wrapped by Error
+    ErrBadFile      = errors.New("invalid or unreadable octree file")
+    ErrBadMethod    = errors.New("unrecognized partitioning method")
+    ErrDuplicateKey = errors.New("the data-point identifier is already in the octree")
+    ErrEmptyTree    = errors.New("there's no octree")
+    ErrKeyNotFound  = errors.New("the data-point identifier is not in the octree")
+    ErrNoCoords     = errors.New("the octree holds no point coordinates")
+    ErrNoPoints     = errors.New("there are no points to process")
+    ErrNotConverged = errors.New("maximum number of iterations exceeded")
 )
 
-func Export(file string, compact bool) {
-/*         Purpose : Exports the octree and its meta data to a specified file using the JSON format with or without newlines
- *                   and identations.
+func Convert(inFile, outFile string, compact bool) error {
+/*         Purpose : Converts an exported octree file from the JSON format to the binary one or vice versa.
+ *       Arguments : inFile  = filename of the JSON or binary input,
+ *                   outFile = filename for the output in the other format,
+ *                   compact = boolean flag for compact mode when the output is JSON.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : (*Octree).Export, (*Octree).ExportBinary, fail, hasCoords, importFile
+ *         Remarks : The input's format is recognized by its signature, and its point coordinates, if any, are kept. The
+ *                   default octree is left unchanged.
+ *                   A JSON file written by Export converts to binary and back to the very same bytes, given the same
+ *                   compact flag, as does a binary file converted to JSON and back.
+ *                   As for Import, the partitioning method must be a built-in one or have been added with RegisterMethod.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if outFile == "" { return fail(ErrBadArgument, "the output filename was not specified") }
+
+    t, isBinary, err := importFile(inFile)
+    if err != nil { return err }
+    if isBinary { return t.Export(outFile, compact, t.hasCoords()) }
+    return t.ExportBinary(outFile, t.hasCoords())
+} //end func Convert
+func Delete(key string) error {
+/*         Purpose : Deletes a data point from the default octree.
+ *       Arguments : key = data-point identifier.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).Delete
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().Delete(key)
+} //end func Delete
+func Export(file string, compact, points bool) error {
+/*         Purpose : Exports the default octree and its meta data to a specified file using the JSON format with or without
+ *                   newlines and identations, and with or without the point coordinates.
  *       Arguments : file    = filename for the JSON output,
- *                   compact = boolean flag for compact mode.
- *         Returns : None.
- * Externals -  In : jsonNode, jsonOctree, _octree, _stats
+ *                   compact = boolean flag for compact mode,
+ *                   points  = boolean flag for embedding the point coordinates of the leaf nodes.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).Export
+ *         Remarks : None.
+ *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Wrapper over the default octree.
+ */
+    return getDefault().Export(file, compact, points)
+} //end func Export
+func ExportBinary(file string, points bool) error {
+/*         Purpose : Exports the default octree and its meta data to a specified file using the binary format, with or
+ *                   without the point coordinates.
+ *       Arguments : file   = filename for the binary output,
+ *                   points = boolean flag for embedding the point coordinates of the leaf nodes.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).ExportBinary
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().ExportBinary(file, points)
+} //end func ExportBinary
+func Histogram(plotWidth, plotHeight int, pngFile string) error {
+/*         Purpose : Plots a histogram of the default octree's leaf point counts and saves it to the specified PNG file.
+ *                   The population mean (mu) and standard deviation (sigma) of the leaf counts are also illustrated.
+ *       Arguments : plotWidth  = plot width in pixels
+ *                   plotHeight = plot height in pixels
+ *                   pngFile    = filename for the resulting PNG histogram plot.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).Histogram
+ *         Remarks : None.
+ *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Wrapper over the default octree.
+ */
+    return getDefault().Histogram(plotWidth, plotHeight, pngFile)
+} //end func Histogram
+func Import(file string) (*Octree, error) {
+/*         Purpose : Imports an octree and its meta data from the specified JSON or binary file and makes it the default
+ *                   octree.
+ *       Arguments : file = data filename.
+ *         Returns : the imported octree, or nil and an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : importFile, setDefault
+ *         Remarks : The file's format is recognized by its signature. See (*Octree).ReadFrom & (*Octree).ReadBinary.
+ *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Returns the imported octree. Streams the file. Reads the binary format.
+ */
+    t, _, err := importFile(file)
+    if err != nil { return nil, err }
+    setDefault(t)
+    return t, nil
+} //end func Import
+func InBox(min, max DataCoords) ([]string, error) {
+/*         Purpose : Searches the default octree for the data points lying inside an axis-aligned box.
+ *       Arguments : min = R^3 coordinates of the box's lower corner,
+ *                   max = R^3 coordinates of the box's upper corner.
+ *         Returns : the data-point identifiers, or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).InBox
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().InBox(min, max)
+} //end func InBox
+func Insert(key string, pt DataCoords) error {
+/*         Purpose : Inserts a data point into the default octree.
+ *       Arguments : key = data-point identifier,
+ *                   pt  = R^3 coordinates of the data point.
+ *         Returns : nil or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).Insert
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().Insert(key, pt)
+} //end func Insert
+func KNearest(pt DataCoords, k int) ([]Neighbor, error) {
+/*         Purpose : Searches the default octree for the k data points nearest to the specified query point.
+ *       Arguments : pt = R^3 coordinates of the query point,
+ *                   k  = number of neighbours sought (>0).
+ *         Returns : the neighbours sorted by increasing distance, or an *Error.
+ * Externals -  In : DataCoords, Neighbor
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).KNearest
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().KNearest(pt, k)
+} //end func KNearest
+func Make(cfg Config, refPoints *DataSet) (*Octree, error) {
+/*         Purpose : Creates a point-region octree recursively using a specified configuration and makes it the default
+ *                   octree.
+ *       Arguments : cfg       = build configuration: partitioning method, termination criterion, limits, etc.
+ *                   refPoints = reference to the map of float64 data points in R^3, keyed on string identifiers.
+ *         Returns : the new octree, or nil and an *Error.
+ * Externals -  In : Config, DataSet
+ * Externals - Out : None.
+ *       Functions : MakeContext
+ *         Remarks : See New for the configuration and the octree's structure.
+ *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Returns the new octree. Takes a build configuration.
+ */
+    return MakeContext(context.Background(), cfg, refPoints)
+} //end func Make
+func MakeContext(ctx context.Context, cfg Config, refPoints *DataSet) (*Octree, error) {
+/*         Purpose : Creates a point-region octree recursively using a specified configuration and makes it the default
+ *                   octree, the build being stopped should the context be cancelled.
+ *       Arguments : ctx       = context whose cancellation or deadline stops the build,
+ *                   cfg       = build configuration: partitioning method, termination criterion, limits, etc.
+ *                   refPoints = reference to the map of float64 data points in R^3, keyed on string identifiers.
+ *         Returns : the new octree, or nil and an *Error.
+ * Externals -  In : Config, DataSet
+ * Externals - Out : None.
+ *       Functions : NewContext, setDefault
+ *         Remarks : See NewContext for the cancellation. The default octree is only replaced once the build completes.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    t, err := NewContext(ctx, cfg, refPoints)
+    if err != nil { return nil, err }
+    setDefault(t)
+    return t, nil
+} //end func MakeContext
+func New(cfg Config, refPoints *DataSet) (*Octree, error) {
+/*         Purpose : Creates a point-region octree recursively using a specified configuration.
+ *       Arguments : cfg       = build configuration, see NewContext for its fields.
+ *                   refPoints = reference to the map of float64 data points in R^3, keyed on string identifiers.
+ *         Returns : the new octree, or nil and an *Error.
+ * Externals -  In : Config, DataSet
+ * Externals - Out : None.
+ *       Functions : NewContext
+ *         Remarks : See NewContext for the configuration and the octree's structure.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return NewContext(context.Background(), cfg, refPoints)
+} //end func New
+func NewContext(ctx context.Context, cfg Config, refPoints *DataSet) (*Octree, error) {
+/*         Purpose : Creates a point-region octree recursively using a specified configuration, the build being stopped
+ *                   should the context be cancelled.
+ *       Arguments : ctx       = context whose cancellation or deadline stops the build,
+ *                   cfg       = build configuration, a zero field selecting its default:
+ *                               METHOD        => partitioning method: 'Centroid', 'DataMidPoint', 'Geometric Median',
+ *                                                'Regular', 'XYZ Medians' or a name added with RegisterMethod,
+ *                               TERMINAL_N    => termination criterion: maximum number of points in a leaf node (>1),
+ *                               TOL           => convergence tolerance for the geometric-median method: 1.0E-12,
+ *                               MAXITERATIONS => maximum number of iterations for the geometric-median method: 1,000,
+ *                               MAXDEPTH      => maximum depth of a node, beyond which it becomes a leaf: no limit,
+ *                               MINCELLSIZE   => extent of a node's points along every axis at or below which it becomes
+ *                                                a leaf: none,
+ *                               PADCUBE       => flag for padding the regular method's root cube to a power-of-two side,
+ *                               SPARSE        => flag for leaving out the empty octants, their links being -1, instead
+ *                                                of allocating empty leaf nodes for them,
+ *                               WORKERS       => maximum number of goroutines building the octree: number of CPUs,
+ *                               PROGRESS      => callback reporting the build's progress, e.g., ProgressBar(os.Stdout):
+ *                                                none.
+ *                   refPoints = reference to the map of float64 data points in R^3, keyed on string identifiers.
+ *         Returns : the new octree, or nil and an *Error.
+ * Externals -  In : Config, DataSet
+ * Externals - Out : None.
+ *       Functions : (*builder).build, calcStats, cubeBounds, fail, makeBuilder, (Config).resolve, rootBounds
+ *         Remarks : The context is checked before each node is built. Once it is cancelled or its deadline passes, all
+ *                   the goroutines of the build return and an *Error wrapping ctx.Err() is returned, for use with
+ *                   errors.Is(err, context.Canceled) or errors.Is(err, context.DeadlineExceeded), nothing being kept of
+ *                   the partial octree.
+ *                   The configuration is private to the octree, its settings affecting no other build, and is recorded,
+ *                   the defaults filled in, in the exported meta data.
+ *                   The data set is copied once into parallel slices of keys and coordinates which are then partitioned in
+ *                   place, node by node, so that each leaf references a contiguous range of them.
+ *                   The regular method splits the bounding cube of the data points, padded to a power-of-two side if
+ *                   PADCUBE is set, and then every child cube at its geometric center, a node's extent thus following from
+ *                   its depth alone.
+ *                   A node whose partition would send all its points to a single octant, e.g., duplicate points, becomes
+ *                   a leaf exceeding the termination criterion, as does one at MAXDEPTH or no larger than MINCELLSIZE.
+ *                   The geometric median is estimated with the Vardi-Zhang modification of Weiszfeld's algorithm, the XYZ
+ *                   medians being used instead should an estimate turn out to be NaN or infinite.
+ *                   With the regular method, a node is only made a leaf in this way if its points coincide or its cube
+ *                   can no longer be halved, MAXDEPTH and MINCELLSIZE otherwise bounding the depth of sparse regions.
+ *                   Subtrees are built concurrently by up to WORKERS goroutines, the resulting layout being identical to
+ *                   that of a serial build. The data points are taken in key order, so that the same data set and
+ *                   configuration always give the same layout regardless of the map's iteration order. The PROGRESS
+ *                   callback is invoked as each node is created, one call at a time, and should return promptly as the
+ *                   build waits on it.
+ *                   The resulting octree is a slice of structures constituting a top-down multi-link node list. Each
+ *                   slice element stores a node's characteristics as follows:
+ *                   N        => number of data points associated with the node (all nodes),
+ *                   CENTER   => array with the partition point coordinates (parent node),
+ *                   CHILDREN => array of octree indices for the corresponding child nodes, -1 for an empty
+ *                               octant left out of a sparse octree (parent node),
+ *                   KEYS     => slice of point identifiers from the given data set (leaf node),
+ *                   PTS      => slice of the point coordinates in the order of KEYS (leaf node),
+ *                               both slices being capped so that appending to them never overwrites another leaf's,
+ *                   LEAF     => flag for a leaf node (all nodes).
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    cfg, err := cfg.resolve()
+    if err != nil { return nil, err }
+    if refPoints == nil || len(*refPoints) == 0 { return nil, fail(ErrNoPoints, "there are no points to process") }
+
+    t            := &Octree{ config: cfg }
+    builder, err := makeBuilder(ctx, cfg, len(*refPoints), &(t.median)) //create octree builder
+    if err != nil { return nil, err }
+    t.config.PROGRESS = nil                                            //release the callback, held by the builder
+    t.stats.HOW   = cfg.METHOD                                         //record partitioning method
+    t.stats.STOP  = cfg.TERMINAL_N                                     //record termination criterion
+
+    start        := time.Now()                                         //record start of execution
+    keys         := make([]string, 0, len(*refPoints))                 //flatten the data set for in-place partitioning,
+    pts          := make([]DataCoords, len(*refPoints))                //in key order for a reproducible layout
+    for k := range *refPoints { keys = append(keys, k) }
+    sort.Strings(keys)
+    for i, k := range keys { pts[i] = (*refPoints)[k] }
+    bounds       := rootBounds()                                       //set the root node's extent
+    if builder.regular {
+        bounds        = cubeBounds(pts, cfg.PADCUBE)
+        t.stats.CUBE  = &bounds                                        //record the root cube
+    }
+    t.nodes, err  = builder.build(keys, pts, nil, bounds, 0)           //build the octree
+    if err != nil { return nil, err }
+    t.stats.TIME  = time.Since(start)                                  //get execution time
+    t.stats.SIZE  = len(t.nodes)                                       //get total number of nodes
+    t.calcStats()                                                      //calc various stats
+    return t, nil
+} //end func NewContext
+func ProgressBar(writer io.Writer) func(Progress) {
+/*         Purpose : Returns a progress callback drawing a console progress bar to the specified writer.
+ *       Arguments : writer = destination of the progress bar, e.g., os.Stdout.
+ *         Returns : the callback, for use as a build configuration's PROGRESS field.
+ * Externals -  In : Progress
+ * Externals - Out : None.
+ *       Functions : updateProgressBar
+ *         Remarks : The bar is redrawn with carriage returns only when its length changes, and is erased once all the
+ *                   data points are placed. The callback is meant for a single build at a time.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    drawn := -1
+    return func(p Progress) {
+        amount := int(0.1 + float32(_progressBarLen) * float32(p.POINTS) / float32(p.TOTAL))
+        if amount == drawn && p.POINTS < p.TOTAL { return }
+        drawn = amount
+        updateProgressBar(writer, "octree.Make:", p.POINTS, p.TOTAL)
+    }
+} //end func ProgressBar
+func Query(refQueryPt *DataCoords) ([]string, error) {
+/*         Purpose : Traverses the default octree top-down to get the data keys at the leaf node corresponding to the octant
+ *                   in which lies the specified query point.
+ *       Arguments : refQueryPt = reference to the R^3 coordinates of the query point.
+ *         Returns : a slice of data-point identifiers, or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).Query
+ *         Remarks : None.
+ *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Wrapper over the default octree.
+ */
+    return getDefault().Query(refQueryPt)
+} //end func Query
+func ReadFrom(reader io.Reader) (*Octree, error) {
+/*         Purpose : Imports an octree and its meta data from a JSON stream, one node at a time, and makes it the default
+ *                   octree.
+ *       Arguments : reader = source of the JSON data, e.g., an HTTP request body.
+ *         Returns : the imported octree, or nil and an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : (*Octree).ReadFrom, setDefault
+ *         Remarks : See (*Octree).ReadFrom.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    t := new(Octree)
+    if _, err := t.ReadFrom(reader); err != nil { return nil, err }
+    setDefault(t)
+    return t, nil
+} //end func ReadFrom
+func RegisterMethod(name string, calcCenter func([]DataCoords) DataCoords) error {
+/*         Purpose : Adds a custom partitioning method computing a node's partition point from the coordinates of its data
+ *                   points.
+ *       Arguments : name       = name of the partitioning method, as passed to Make & New and recorded by Export,
+ *                   calcCenter = function returning the partition point for a slice of data points.
+ *         Returns : nil or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : _methods
+ *       Functions : fail, makeCalcCenter
+ *         Remarks : The built-in methods cannot be redefined and a name can only be added once. As subtrees are built
+ *                   concurrently, calcCenter must be safe for concurrent use; it must not modify the slice it is given,
+ *                   which is never empty. A partition point with a NaN ordinate fails the build with ErrBadMethod.
+ *                   Methods must be added before importing an octree that uses them.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if name == ""        { return fail(ErrBadArgument, "the method name was not specified") }
+    if calcCenter == nil { return fail(ErrBadArgument, "the partition-point function was not specified") }
+    if _, err := makeCalcCenter(Config{ METHOD: name }, nil); err == nil {
+        return fail(ErrBadArgument, "the method name '" + name + "' is already defined")
+    }
+
+    _methodsMu.Lock()
+    defer _methodsMu.Unlock()
+    if _, found := _methods[name]; found { return fail(ErrBadArgument, "the method name '" + name + "' is already defined") }
+    _methods[name] = calcCenter
+    return nil
+} //end func RegisterMethod
+func WithinRadius(center DataCoords, r float64) ([]string, error) {
+/*         Purpose : Searches the default octree for the data points lying within a given distance of a specified point.
+ *       Arguments : center = R^3 coordinates of the sphere's center,
+ *                   r      = radius of the sphere (>=0).
+ *         Returns : the data-point identifiers, or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).WithinRadius
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().WithinRadius(center, r)
+} //end func WithinRadius
+func Summarize(output ...string) error {
+/*         Purpose : Outputs the meta data and various statistics regarding the default octree to a specified file or Stdout.
+ *       Arguments : output[0] = optional output filename; the default is os.Stdout
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : getDefault, (*Octree).Summarize
+ *         Remarks : None.
+ *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Wrapper over the default octree.
+ */
+    return getDefault().Summarize(output...)
+} //end func Summarize
+func WriteTo(writer io.Writer) (int64, error) {
+/*         Purpose : Exports the default octree and its meta data to a JSON stream in compact mode, one node at a time.
+ *       Arguments : writer = destination of the JSON data, e.g., an HTTP response or an upload stream.
+ *         Returns : the number of bytes written, and nil or an *Error.
+ * Externals -  In : None.
  * Externals - Out : None.
- *       Functions : halt
+ *       Functions : getDefault, (*Octree).WriteTo
  *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return getDefault().WriteTo(writer)
+} //end func WriteTo
+////Octree methods
+func (t *Octree) Config() Config {
+/*         Purpose : Returns the configuration with which the octree was built.
+ *       Arguments : None.
+ *         Returns : the build configuration, the defaults filled in.
+ * Externals -  In : Config
+ * Externals - Out : None.
+ *       Functions : None.
+ *         Remarks : Passing it to New with the same data set rebuilds the same octree. For an octree imported from a v1
+ *                   file, the limits are the defaults. The progress callback is not retained.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t == nil { return Config{} }
+    return t.config
+} //end func (*Octree).Config
+func (t *Octree) Delete(key string) error {
+/*         Purpose : Deletes a data point from the octree.
+ *       Arguments : key = data-point identifier.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : collapse, compact, fail, indexKeys
+ *         Remarks : The point is removed from its leaf node and the point counts of the leaf's ancestors, found from the
+ *                   parent links kept with the key index, are updated. The point coordinates aren't needed.
+ *                   Should the count of a parent node fall to the termination criterion or below, its whole subtree
+ *                   is merged back into a single leaf and the octree's nodes are renumbered. A leaf emptied in a sparse
+ *                   octree is left out likewise.
+ *                   Deletions must not run concurrently with any other use of the octree.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty() { return fail(ErrEmptyTree, "there's no octree to delete from") }
+    t.indexKeys()
+    leafIdx, found := t.index[key]
+    if !found { return fail(ErrKeyNotFound, "unknown data-point identifier '" + key + "'") }
+
+    var(
+        collapseIdx = -1                 //index of the topmost parent node to collapse
+        leaf        = &t.nodes[leafIdx]  //leaf node holding the point
+        path        []int                //indices of the leaf's ancestors, bottom-up
+        ptIdx       int                  //index of the point in the leaf
+    )
+    for k := t.parents[leafIdx]; k >= 0; k = t.parents[k] { path = append(path, k) }
+    for leaf.KEYS[ptIdx] != key { ptIdx++ }
+    //Remove the point from its leaf
+    leaf.KEYS = append(leaf.KEYS[:ptIdx], leaf.KEYS[ptIdx+1:]...)
+    if len(leaf.PTS) == leaf.N { leaf.PTS = append(leaf.PTS[:ptIdx], leaf.PTS[ptIdx+1:]...) }
+    leaf.N--
+    delete(t.index, key)
+    //Update the ancestors, collapsing the topmost one no longer exceeding the termination criterion
+    for i := len(path) - 1; i >= 0; i-- {
+        t.nodes[path[i]].N--
+        if collapseIdx < 0 && t.nodes[path[i]].N <= t.stats.STOP { collapseIdx = path[i] }
+    }
+    switch {
+        case collapseIdx >= 0:
+            t.collapse(collapseIdx)
+            t.compact()
+        case t.config.SPARSE && leaf.N == 0 && len(path) > 0: //leave out the emptied octant
+            links := &(t.nodes[path[0]].CHILDREN)
+            for octant := range links {
+                if links[octant] == leafIdx { links[octant] = -1 }
+            }
+            t.compact()
+    }
+    t.stats.SIZE, t.stale = len(t.nodes), true
+    return nil
+} //end func (*Octree).Delete
+func (t *Octree) Export(file string, compact, points bool) error {
+/*         Purpose : Exports the octree and its meta data to a specified file using the JSON format with or without newlines
+ *                   and identations, and with or without the point coordinates.
+ *       Arguments : file    = filename for the JSON output,
+ *                   compact = boolean flag for compact mode,
+ *                   points  = boolean flag for embedding the point coordinates of the leaf nodes.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : fail, hasCoords, (*Octree).WriteJSON
+ *         Remarks : The nodes are streamed one at a time through a buffered writer, the output being the same as that of
+ *                   marshalling the whole octree at once.
+ *                   With the point coordinates, the imported octree supports every query & insertion, and the data set
+ *                   can be reconstructed from it. Imported octrees only have them if their file did.
  *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Method of the Octree type. Streams the nodes. Embeds the coordinates.
  */
-    if _stats.SIZE == 0  { halt("there's no octree to export") }
-    if file        == "" { halt("the filename was not specified") }
+    if t.isEmpty()  { return fail(ErrEmptyTree, "there's no octree to export") }
+    if file  == ""  { return fail(ErrBadArgument, "the filename was not specified") }
+    if points && !t.hasCoords() { return fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
 
-    var output []byte
+    writer, err := os.Create(file) //open file for write
+    if err != nil { return fail(err, "os.Create - " + err.Error()) }
+    defer writer.Close()
+
+    buffer := bufio.NewWriter(writer)
+    if _, err = t.WriteJSON(buffer, compact, points); err != nil { return err } //save the tree
+    if err = buffer.Flush(); err != nil { return fail(err, "buffer.Flush - " + err.Error()) }
+    if err = writer.Sync(); err != nil { return fail(err, "writer.Sync - " + err.Error()) }
+    if err = writer.Close(); err != nil { return fail(err, "writer.Close - " + err.Error()) }
+    return nil
+} //end func (*Octree).Export
+func (t *Octree) ExportBinary(file string, points bool) error {
+/*         Purpose : Exports the octree and its meta data to a specified file using the binary format, with or without the
+ *                   point coordinates.
+ *       Arguments : file   = filename for the binary output,
+ *                   points = boolean flag for embedding the point coordinates of the leaf nodes.
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : fail, hasCoords, (*Octree).WriteBinary
+ *         Remarks : See (*Octree).WriteBinary for the format.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty()  { return fail(ErrEmptyTree, "there's no octree to export") }
+    if file  == ""  { return fail(ErrBadArgument, "the filename was not specified") }
+    if points && !t.hasCoords() { return fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
 
     writer, err := os.Create(file) //open file for write
-    if err != nil { halt("os.Create - " + err.Error()) }
+    if err != nil { return fail(err, "os.Create - " + err.Error()) }
     defer writer.Close()
 
-    nodeData := make([]jsonNode, _stats.SIZE)
-    for k, v := range _octree {
-        if v.N > _stats.STOP { //parent node
-            nodeData[k] = jsonNode{ ID:       k,
-                                    N:        v.N,
-                                    CENTER:   &(_octree[k].CENTER),
-                                    CHILDREN: &(_octree[k].CHILDREN) }
-        } else { //leaf node
-            nodeData[k] = jsonNode{ ID:       k,
-                                    N:        v.N,
-                                    KEYS:     v.KEYS }
-        }
-    }
-    jsonData := jsonOctree{ HOW:    _stats.HOW,
-                            STOP:   _stats.STOP,
-                            TIME:   _stats.TIME,
-                            SIZE:   _stats.SIZE,
-                            OCTREE: nodeData }
-    if compact { output, err = json.Marshal(jsonData) // create the JSON output
-    } else     { output, err = json.MarshalIndent(jsonData, "", " ") }
-    if err != nil { halt("Marshal/MarshalIndent - " + err.Error()) }
-
-    _, err = writer.Write(output) //save the tree
-    if err != nil { halt("writer.Write - " + err.Error()) }
-    if err = writer.Sync(); err != nil { halt("writer.Sync - " + err.Error()) }
-    if err = writer.Close(); err != nil { halt("writer.Close - " + err.Error()) }
-    return
-} //end func Export
-func Histogram(plotWidth, plotHeight int, pngFile string) {
+    buffer := bufio.NewWriter(writer)
+    if _, err = t.WriteBinary(buffer, points); err != nil { return err } //save the tree
+    if err = buffer.Flush(); err != nil { return fail(err, "buffer.Flush - " + err.Error()) }
+    if err = writer.Sync(); err != nil { return fail(err, "writer.Sync - " + err.Error()) }
+    if err = writer.Close(); err != nil { return fail(err, "writer.Close - " + err.Error()) }
+    return nil
+} //end func (*Octree).ExportBinary
+func (t *Octree) Histogram(plotWidth, plotHeight int, pngFile string) error {
 /*         Purpose : Plots a histogram of the leaf point counts and saves it to the specified PNG file.
  *                   The population mean (mu) and standard deviation (sigma) of the leaf counts are also illustrated.
  *       Arguments : plotWidth  = plot width in pixels
  *                   plotHeight = plot height in pixels
  *                   pngFile    = filename for the resulting PNG histogram plot.
- *         Returns : None.
- * Externals -  In : _stats
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
  * Externals - Out : None.
- *       Functions : halt
+ *       Functions : fail
  *         Remarks : Creates a temp file for the histogram data which will be deleted on return.
  *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Method of the Octree type.
  */
-    if _stats.SIZE == 0  { halt("there's no octree to process") }
-    if plotWidth   == 0  { halt("the plot width was not specified") }
-    if plotHeight  == 0  { halt("the plot height was not specified") }
-    if pngFile     == "" { halt("filename for the PNG histogram plot was not specified") }
+    if t.isEmpty()     { return fail(ErrEmptyTree, "there's no octree to process") }
+    if plotWidth  == 0 { return fail(ErrBadArgument, "the plot width was not specified") }
+    if plotHeight == 0 { return fail(ErrBadArgument, "the plot height was not specified") }
+    if pngFile    == "" { return fail(ErrBadArgument, "filename for the PNG histogram plot was not specified") }
 
+    if t.stale { t.calcStats() }
+    stats := &t.stats
     //Write the leaf counts to a temporary file
     refTemp, err := ioutil.TempFile("", "octree_")
-    if err != nil { halt("ioutil.TempFile - " + err.Error()) }
+    if err != nil { return fail(err, "ioutil.TempFile - " + err.Error()) }
     histoData   := filepath.ToSlash(refTemp.Name())
     writer, err := os.Create(histoData)
-    if err != nil { halt("os.Create - " + err.Error()) }
+    if err != nil { return fail(err, "os.Create - " + err.Error()) }
     defer writer.Close()
-    for _, v := range _stats.LEAFCOUNTS { fmt.Fprintf(writer, "%v\n", v) }
-    if err = writer.Sync(); err != nil { halt("writer.Sync - " + err.Error()) }
-    if err = writer.Close(); err != nil { halt("writer.Close - " + err.Error()) }
+    for _, v := range stats.LEAFCOUNTS { fmt.Fprintf(writer, "%v\n", v) }
+    if err = writer.Sync(); err != nil { return fail(err, "writer.Sync - " + err.Error()) }
+    if err = writer.Close(); err != nil { return fail(err, "writer.Close - " + err.Error()) }
     //Compose the gnuplot commands
     plotCmds := []string{
                  fmt.Sprintf("set terminal pngcairo dashed enhanced size %d,%d", plotWidth, plotHeight),
                  fmt.Sprintf(`set output "%s"`, pngFile),
-                 fmt.Sprintf("set xrange [%d:%d]", _stats.XMIN - 1, _stats.XMAX + 1),
+                 fmt.Sprintf("set xrange [%d:%d]", stats.XMIN - 1, stats.XMAX + 1),
                  "set yrange [0:]",
                  "set tics out nomirror",
                  `set grid back lt 0 lw 1 lc rgb "black"`,
                  fmt.Sprintf(`set title "Histogram of %s points into %s leaf nodes.\n(%s, %s points, %s)`,
-                             _stats.NUMPTS, _stats.NUMLEAVES, _stats.HOW, _stats.TERMINAL_N, _stats.TIME),
+                             stats.NUMPTS, stats.NUMLEAVES, stats.HOW, stats.TERMINAL_N, stats.TIME),
                  fmt.Sprintf(`set xlabel "\nLeaf Point Count\n({/Symbol m}=%s, {/Symbol s}=%s, {/Symbol m}-{/Symbol s}=%s, {/Symbol m}+{/Symbol s}=%s)"`,
-                             _stats.MU, _stats.SIGMA,  _stats.LOWER, _stats.UPPER),
+                             stats.MU, stats.SIGMA,  stats.LOWER, stats.UPPER),
                  `set ylabel "Frequency"`,
                  `set style arrow 1 nohead lt 3 lc rgb "blue"`,
                  `set style arrow 2 nohead lt 4 lc rgb "blue"`,
                  `set style arrow 3 filled lt 1 lc rgb "blue"`,
                  `set style arrow 4 nohead lt 1 lc rgb "red" lw 3 front`,
                  //mean - std dev
-                 fmt.Sprintf("set arrow as 2 from %s,0 to %s,graph 1", _stats.LOWER, _stats.LOWER),
-                 fmt.Sprintf("set arrow as 3 from %s,character 3.5 to %s,0", _stats.LOWER, _stats.LOWER),
-                 fmt.Sprintf(`set label "{/Symbol m}-{/Symbol s}" at %s,character 3 center tc rgb "blue"`, _stats.LOWER),
+                 fmt.Sprintf("set arrow as 2 from %s,0 to %s,graph 1", stats.LOWER, stats.LOWER),
+                 fmt.Sprintf("set arrow as 3 from %s,character 3.5 to %s,0", stats.LOWER, stats.LOWER),
+                 fmt.Sprintf(`set label "{/Symbol m}-{/Symbol s}" at %s,character 3 center tc rgb "blue"`, stats.LOWER),
                  //mean
-                 fmt.Sprintf("set arrow as 1 from %s,0 to %s,graph 1", _stats.MU, _stats.MU),
-                 fmt.Sprintf("set arrow as 3 from %s,character 3.5 to %s,0", _stats.MU, _stats.MU),
-                 fmt.Sprintf(`set label "{/Symbol m}" at %s,character 3 center tc rgb "blue"`, _stats.MU ),
+                 fmt.Sprintf("set arrow as 1 from %s,0 to %s,graph 1", stats.MU, stats.MU),
+                 fmt.Sprintf("set arrow as 3 from %s,character 3.5 to %s,0", stats.MU, stats.MU),
+                 fmt.Sprintf(`set label "{/Symbol m}" at %s,character 3 center tc rgb "blue"`, stats.MU ),
                  //mean + std dev
-                 fmt.Sprintf("set arrow as 2 from %s,0 to %s,graph 1", _stats.UPPER, _stats.UPPER),
-                 fmt.Sprintf("set arrow as 3 from %s,character 3.5 to %s,0", _stats.UPPER, _stats.UPPER),
-                 fmt.Sprintf(`set label "{/Symbol m}+{/Symbol s}" at %s,character 3 center tc rgb "blue"`, _stats.UPPER),
+                 fmt.Sprintf("set arrow as 2 from %s,0 to %s,graph 1", stats.UPPER, stats.UPPER),
+                 fmt.Sprintf("set arrow as 3 from %s,character 3.5 to %s,0", stats.UPPER, stats.UPPER),
+                 fmt.Sprintf(`set label "{/Symbol m}+{/Symbol s}" at %s,character 3 center tc rgb "blue"`, stats.UPPER),
                  //warn about any empty leaves
-                 "set arrow as 4 from 0,0 to 0," + _stats.NUMEMPTY,
+                 "set arrow as 4 from 0,0 to 0," + stats.NUMEMPTY,
                  //frequency vs count
                  `plot "` + histoData + `" u 1:(1) smooth freq w impulses lw 3 lc rgb "#228B22" notitle`,
                  "quit" }
     //Send the commands to gnuplot
     plotter, err := gnuplot.NewPlotter("", false, false)
-    if err != nil { halt("gnuplot.NewPlotter - " + err.Error()) }
-    for _, v := range plotCmds { plotter.CheckedCmd("%s", v) }
+    if err != nil { return fail(err, "gnuplot.NewPlotter - " + err.Error()) }
+    for _, v := range plotCmds {
+        if err = plotter.CheckedCmd("%s", v); err != nil {
+            plotter.Close()
+            os.Remove(histoData)
+            return fail(err, "plotter.CheckedCmd - " + err.Error())
+        }
+    }
     plotter.Close()
     //Delete the temp file
     err = os.Remove(histoData)
@@ -183,117 +801,455 @@ func Histogram(plotWidth, plotHeight int, pngFile string) {
         time.Sleep(time.Millisecond)
         err = os.Remove(histoData)
     }
-    if err != nil { halt("os.Remove - " + err.Error()) }
-} //end func Histogram
-func Import(file string) {
-/*         Purpose : Imports an octree and its meta data from the specified JSON file.
- *       Arguments : file = data filename.
- *         Returns : None.
- * Externals -  In : node
- * Externals - Out : _octree, _stats
- *       Functions : calcStats, halt
- *         Remarks : None.
- *         History : v1.0.0 - October 26, 2016 - Original release.
+    if err != nil { return fail(err, "os.Remove - " + err.Error()) }
+    return nil
+} //end func (*Octree).Histogram
+func (t *Octree) InBox(min, max DataCoords) ([]string, error) {
+/*         Purpose : Searches the octree for the data points lying inside an axis-aligned box.
+ *       Arguments : min = R^3 coordinates of the box's lower corner,
+ *                   max = R^3 coordinates of the box's upper corner.
+ *         Returns : the data-point identifiers, or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : collectKeys, fail, (cellBounds).child, (cellBounds).inside, (cellBounds).outside, rootBounds
+ *         Remarks : The box is closed, i.e., points on its faces are included. The octants are bounded by the partition
+ *                   points of their ancestors with the tie rule of assignOctant: a point on a partition plane belongs
+ *                   to the lower octant. An octant lying outside the box is skipped whereas one lying inside it is
+ *                   accepted whole.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
  */
-    if fi, err := os.Stat(file); (err != nil) || (fi.Size() == 0) {
-        halt("the input file cannot be located or is empty")
+    if t.isEmpty() { return nil, fail(ErrEmptyTree, "there's no octree to query") }
+    for k := range min {
+        if !(min[k] <= max[k]) { return nil, fail(ErrBadArgument, fmt.Sprintf("invalid box extent '%v' to '%v'", min, max)) }
     }
 
-    var jsonIn jsonOctree
-
-    input, err := ioutil.ReadFile(file) //read the whole file
-    if err != nil { halt("ioutil.ReadFile - " + err.Error()) }
+    var(
+        keys  []string
+        stack = []searchItem{ { NODE: 0, BOUNDS: rootBounds() } }
+    )
+    for len(stack) > 0 {
+        item    := stack[len(stack)-1]
+        stack    = stack[:len(stack)-1]
+        thisNode := &t.nodes[item.NODE]
+        switch {
+            case thisNode.N == 0 || item.BOUNDS.outside(&min, &max): //octant outside the box
+            case item.BOUNDS.inside(&min, &max):                     //octant inside the box
+                keys = t.collectKeys(item.NODE, keys)
+            case !thisNode.LEAF:                                     //parent node straddling the box
+                for octant, childIdx := range thisNode.CHILDREN {
+                    if childIdx < 0 { continue } //empty octant left out
+                    stack = append(stack, searchItem{ NODE: childIdx, BOUNDS: item.BOUNDS.child(&thisNode.CENTER, octant) })
+                }
+            default:                                                 //leaf node straddling the box
+                if len(thisNode.PTS) != thisNode.N {
+                    return nil, fail(ErrNoCoords, "the leaf nodes lack point coordinates")
+                }
+                points: for i, key := range thisNode.KEYS {
+                    for k, v := range thisNode.PTS[i] {
+                        if v < min[k] || v > max[k] { continue points }
+                    }
+                    keys = append(keys, key)
+                }
+        }
+    }
+    return keys, nil
+} //end func (*Octree).InBox
+func (t *Octree) Insert(key string, pt DataCoords) error {
+/*         Purpose : Inserts a data point into the octree.
+ *       Arguments : key = data-point identifier,
+ *                   pt  = R^3 coordinates of the data point.
+ *         Returns : nil or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : assignOctant, (*builder).build, (cellBounds).child, fail, indexKeys, makeBuilder, (*medianDiag).merge,
+ *                   rootBounds, spliceLeaf
+ *         Remarks : The octree is walked down to the leaf node whose octant holds the point, the point is appended to the
+ *                   leaf and the point counts of its ancestors are updated. Should the leaf then exceed the termination
+ *                   criterion, it is split using the octree's build configuration. An empty octant left out of a sparse
+ *                   octree gets a new leaf node. With the regular method, a point lying outside the root cube goes to the
+ *                   leaf of the nearest boundary cube. The octree is left unchanged on error.
+ *                   Insertions must not run concurrently with any other use of the octree.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty() { return fail(ErrEmptyTree, "there's no octree to insert into") }
+    t.indexKeys()
+    if _, found := t.index[key]; found { return fail(ErrDuplicateKey, "duplicate data-point identifier '" + key + "'") }
 
-    err = json.Unmarshal(input, &jsonIn) // decode the JSON data
-    if err != nil { halt("json.Unmarshal - " + err.Error()) }
+    var(
+        bounds  = rootBounds() //extent of the leaf node
+        nodeIdx int            //index of the leaf node
+        path    []int          //indices of the leaf's ancestors
+    )
+    if t.stats.CUBE != nil { bounds = *t.stats.CUBE }
+    for !t.nodes[nodeIdx].LEAF { //while node is a parent node
+        octant := assignOctant(&(t.nodes[nodeIdx].CENTER),&pt)
+        path    = append(path, nodeIdx)
+        bounds  = bounds.child(&(t.nodes[nodeIdx].CENTER), octant)
+        if t.nodes[nodeIdx].CHILDREN[octant] < 0 { //allocate an empty octant left out
+            t.nodes[nodeIdx].CHILDREN[octant] = len(t.nodes)
+            t.nodes   = append(t.nodes, node{ LEAF: true })
+            t.parents = append(t.parents, nodeIdx)
+        }
+        nodeIdx = t.nodes[nodeIdx].CHILDREN[octant]
+    }
+    leaf := &t.nodes[nodeIdx]
+    if len(leaf.PTS) != leaf.N { return fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
+    if leaf.N + 1 > t.stats.STOP { //split the leaf
+        keys := append(append(make([]string, 0, leaf.N + 1), leaf.KEYS...), key)
+        pts  := append(append(make([]DataCoords, 0, leaf.N + 1), leaf.PTS...), pt)
+        var run medianDiag //diagnostics of the split, kept only should it succeed
+        builder, err := makeBuilder(context.Background(), t.config, 0, &run)
+        if err != nil { return err }
+        subtree, err := builder.build(keys, pts, nil, bounds, len(path))
+        if err != nil { return err }
+        t.spliceLeaf(nodeIdx, subtree)
+        t.median.merge(&(run.STATS))
+    } else { //append to the leaf
+        leaf.KEYS    = append(leaf.KEYS, key)
+        leaf.PTS     = append(leaf.PTS, pt)
+        leaf.N++
+        t.index[key] = nodeIdx
+    }
+    for _, k := range path { t.nodes[k].N++ }
+    t.stats.SIZE, t.stale = len(t.nodes), true
+    return nil
+} //end func (*Octree).Insert
+func (t *Octree) KNearest(pt DataCoords, k int) ([]Neighbor, error) {
+/*         Purpose : Searches the octree for the k data points nearest to the specified query point.
+ *       Arguments : pt = R^3 coordinates of the query point,
+ *                   k  = number of neighbours sought (>0).
+ *         Returns : the neighbours sorted by increasing distance, or an *Error.
+ * Externals -  In : DataCoords, Neighbor
+ * Externals - Out : None.
+ *       Functions : fail, (cellBounds).child, (cellBounds).distance2, rootBounds
+ *         Remarks : Best-first search: the octants and the data points are visited in increasing order of their distance
+ *                   to the query point, an octant's distance being that of its nearest face as implied by the partition
+ *                   points of its ancestors. Fewer than k neighbours are returned if the octree holds fewer points.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty() { return nil, fail(ErrEmptyTree, "there's no octree to query") }
+    if k < 1       { return nil, fail(ErrBadArgument, fmt.Sprintf("invalid number of neighbours '%d'", k)) }
 
-    _stats.HOW     = jsonIn.HOW
-    _stats.SIZE    = jsonIn.SIZE
-    _stats.STOP    = jsonIn.STOP
-    _stats.TIME    = jsonIn.TIME
-    _octree        = make([]node, _stats.SIZE, _stats.SIZE)
-    for k, v := range jsonIn.OCTREE {
-        _octree[k].N = v.N
-        if v.N > _stats.STOP { //parent node
-            _octree[k].CENTER, _octree[k].CHILDREN = *(v.CENTER), *(v.CHILDREN)
-        } else { //leaf node
-            _octree[k].KEYS = v.KEYS
+    var(
+        neighbors = make([]Neighbor, 0, mathutil.Min(k, t.nodes[0].N))
+        queue     = &searchQueue{ { NODE: 0, BOUNDS: rootBounds() } }
+    )
+    for queue.Len() > 0 && len(neighbors) < k {
+        item := heap.Pop(queue).(searchItem)
+        if item.NODE < 0 { //data point
+            neighbors = append(neighbors, Neighbor{ KEY: item.KEY, DISTANCE: math.Sqrt(item.DIST2) })
+            continue
+        }
+        thisNode := &t.nodes[item.NODE]
+        if thisNode.N == 0 { continue }
+        if !thisNode.LEAF { //parent node: queue the child octants
+            for octant, childIdx := range thisNode.CHILDREN {
+                if childIdx < 0 { continue } //empty octant left out
+                bounds := item.BOUNDS.child(&thisNode.CENTER, octant)
+                heap.Push(queue, searchItem{ DIST2: bounds.distance2(&pt), NODE: childIdx, BOUNDS: bounds })
+            }
+            continue
+        }
+        if len(thisNode.PTS) != thisNode.N { return nil, fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
+        for i, key := range thisNode.KEYS { //leaf node: queue its data points
+            heap.Push(queue, searchItem{ DIST2: distance2(&pt, &(thisNode.PTS[i])), NODE: -1, KEY: key })
         }
     }
-    calcStats()
-} //end func Import
-func Make(method string, terminal_N int, refPoints *DataSet) {
-/*         Purpose : Creates a point-region octree recursively using a specified partitioning method and termination criterion.
- *       Arguments : method     = partitioning method: 'Centroid', 'DataMidPoint', 'Geometric Median' or 'XYZ Medians'.
- *                   terminal_N = termination criterion: maximum number of points in a leaf node (>1).
- *                   refPoints  = reference to the map of float64 data points in R^3, keyed on string identifiers.
- *         Returns : None.
+    return neighbors, nil
+} //end func (*Octree).KNearest
+func (t *Octree) MedianStats() MedianStats {
+/*         Purpose : Returns the convergence diagnostics of the geometric-median method of partitioning.
+ *       Arguments : None.
+ *         Returns : the diagnostics accumulated over the nodes partitioned by the build and any insertions.
+ * Externals -  In : MedianStats
+ * Externals - Out : None.
+ *       Functions : None.
+ *         Remarks : The diagnostics are zero for the other methods and for an imported octree. ITERATIONS and WORST count
+ *                   the Weiszfeld estimates, the Aitken extrapolations being tallied separately, per axis, as ACCEPTED
+ *                   when the discrepancies between the successive estimates decrease monotonically, REJECTED otherwise.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t == nil { return MedianStats{} }
+    t.median.mu.Lock()
+    defer t.median.mu.Unlock()
+    return t.median.STATS
+} //end func (*Octree).MedianStats
+func (t *Octree) Points() (DataSet, error) {
+/*         Purpose : Reconstructs the data set from the keys & point coordinates held by the leaf nodes.
+ *       Arguments : None.
+ *         Returns : the data set, or nil and an *Error.
  * Externals -  In : DataSet
- * Externals - Out : _builder, _octree, _stats
- *       Functions : calcStats, halt, makeBuilder
- *         Remarks : The resulting octree is a slice of structures constituting a top-down multi-link node list. Each
- *                   slice element stores a node's characteristics as follows:
- *                   N        => number of data points associated with the node (all nodes),
- *                   CENTER   => array with the partition point coordinates (parent node),
- *                   CHILDREN => array of octree indices for the corresponding child nodes (parent node),
- *                   KEYS     => a CSV string of point identifiers from the given data set (leaf node).
- *         History : v1.0.0 - October 26, 2016 - Original release.
+ * Externals - Out : None.
+ *       Functions : fail, hasCoords
+ *         Remarks : An imported octree only holds the point coordinates if they were embedded in its file.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
  */
-    if !(terminal_N > 1)    { halt(fmt.Sprintf("invalid termination criterion '%d'", terminal_N)) }
-    if len(*refPoints) == 0 { halt("there are no points to process") }
-
-    _builder     = makeBuilder(method, terminal_N, len(*refPoints)) //create builder function
-    _stats.HOW   = method                                           //record partitioning method
-    _stats.STOP  = terminal_N                                       //record termination criterion
-    _octree      = nil                                              //clear the octree
+    if t.isEmpty()     { return nil, fail(ErrEmptyTree, "there's no octree to search") }
+    if !t.hasCoords()  { return nil, fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
 
-    start       := time.Now()                                       //record start of execution
-    _builder(refPoints)                                             //build the octree
-    _stats.TIME  = time.Since(start)                                //get execution time
-    _stats.SIZE  = len(_octree)                                     //get total number of nodes
-    calcStats()                                                     //calc various stats
-} //end func Make
-func Query(refQueryPt *DataCoords) string {
-/*         Purpose : Traverses an octree top-down to get the data keys at the leaf node corresponding to the octant in
+    points := make(DataSet)
+    for k := range t.nodes {
+        if !t.nodes[k].LEAF { continue }
+        for i, key := range t.nodes[k].KEYS { points[key] = t.nodes[k].PTS[i] }
+    }
+    return points, nil
+} //end func (*Octree).Points
+func (t *Octree) Query(refQueryPt *DataCoords) ([]string, error) {
+/*         Purpose : Traverses the octree top-down to get the data keys at the leaf node corresponding to the octant in
  *                   which lies the specified query point.
  *       Arguments : refQueryPt = reference to the R^3 coordinates of the query point.
- *         Returns : a CSV string of data-point identifiers.
- * Externals -  In : DataCoords, _octree, _stats
+ *         Returns : a slice of data-point identifiers, or an *Error.
+ * Externals -  In : DataCoords
  * Externals - Out : None.
- *       Functions : assignOctant, halt
- *         Remarks : None.
+ *       Functions : assignOctant, fail
+ *         Remarks : No keys are returned for an empty octant, whether it is an empty leaf node or left out of a sparse
+ *                   octree. The slice is a copy which the caller may modify without affecting the octree.
  *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Method of the Octree type.
  */
-    if _stats.SIZE == 0 { halt("there's no octree to query") }
+    if t.isEmpty() { return nil, fail(ErrEmptyTree, "there's no octree to query") }
 
     var nodeIdx int
-    for _octree[nodeIdx].N > _stats.STOP { //while node is a parent node
-        nodeIdx = _octree[nodeIdx].CHILDREN[assignOctant(&(_octree[nodeIdx].CENTER),refQueryPt)]
+    for !t.nodes[nodeIdx].LEAF { //while node is a parent node
+        nodeIdx = t.nodes[nodeIdx].CHILDREN[assignOctant(&(t.nodes[nodeIdx].CENTER),refQueryPt)]
+        if nodeIdx < 0 { return nil, nil } //empty octant left out
     }
-    return _octree[nodeIdx].KEYS
-} //end func Query
-func Summarize(output ...string) {
+    return append([]string(nil), t.nodes[nodeIdx].KEYS...), nil
+} //end func (*Octree).Query
+func (t *Octree) ReadBinary(reader io.Reader) (int64, error) {
+/*         Purpose : Replaces the octree with one imported from a binary stream.
+ *       Arguments : reader = source of the binary data, preferably buffered.
+ *         Returns : the number of bytes consumed, and nil or an *Error.
+ * Externals -  In : binHeader, Config, jsonOctree, node
+ * Externals - Out : None.
+ *       Functions : fail, restore
+ *         Remarks : See (*Octree).WriteBinary for the format. Only the bytes of the octree are consumed, so that other
+ *                   data may follow it in the stream. The structure is validated as for (*Octree).ReadFrom, each node
+ *                   record being checked for its count, key count & child links as it is read. The keys are read by
+ *                   bounded chunks rather than allocated from their recorded lengths.
+ *                   The point coordinates are restored if the stream holds them.
+ *                   The partitioning method must be a built-in one or have been added with RegisterMethod beforehand.
+ *                   The octree is left unchanged should the stream be invalid or of an unsupported version.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    var(
+        counter = &countReader{ reader: reader }
+        header  binHeader
+        leaves  []int                     //indices of the leaf nodes
+        le      = binary.LittleEndian
+        method  strings.Builder
+        nodes   []node
+        numKeys int64                     //number of keys referenced by the leaf nodes
+        record  [_binaryNodeLen]byte
+    )
+
+    if err := binary.Read(counter, le, &header); err != nil {
+        return counter.n, fail(ErrBadFile, "binary.Read - " + err.Error())
+    }
+    if string(header.MAGIC[:]) != _binaryMagic { return counter.n, fail(ErrBadFile, "the input is not a binary octree") }
+    if header.VERSION != _binaryVersion {
+        return counter.n, fail(ErrBadFile, fmt.Sprintf("unsupported binary format version %d", header.VERSION))
+    }
+    if header.FLAGS & ^uint32(_binaryCube | _binaryPadCube | _binarySparse | _binaryPoints) != 0 {
+        return counter.n, fail(ErrBadFile, "the header has invalid flags")
+    }
+    if header.SIZE < 0 || header.KEYS < 0 { return counter.n, fail(ErrBadFile, "the node or key count is negative") }
+    if _, err := io.CopyN(&method, counter, int64(header.METHODLEN)); err != nil {
+        return counter.n, fail(ErrBadFile, "reading the method - " + err.Error())
+    }
+
+    nodes = make([]node, 0, min(header.SIZE, _binaryPrealloc))
+    for k := int64(0); k < header.SIZE; k++ { //decode the fixed-size node records
+        if _, err := io.ReadFull(counter, record[:]); err != nil {
+            return counter.n, fail(ErrBadFile, fmt.Sprintf("reading node %d - %s", k, err.Error()))
+        }
+        flags := le.Uint32(record[12:])
+        if flags & ^uint32(_binaryLeaf) != 0 {
+            return counter.n, fail(ErrBadFile, fmt.Sprintf("node %d has invalid flags", k))
+        }
+        n := node{ N: int(int64(le.Uint64(record[0:]))), LEAF: flags & _binaryLeaf != 0 }
+        if n.N < 0 { return counter.n, fail(ErrBadFile, fmt.Sprintf("node %d has a negative count of %d", k, n.N)) }
+        if n.LEAF { //leaf node: key count
+            count := int64(le.Uint32(record[8:]))
+            if count != int64(n.N) {
+                return counter.n, fail(ErrBadFile,
+                                       fmt.Sprintf("node %d is a leaf with %d keys for a count of %d", k, count, n.N))
+            }
+            if count > header.KEYS - numKeys {
+                return counter.n, fail(ErrBadFile, fmt.Sprintf("node %d references keys beyond the key table", k))
+            }
+            n.KEYS   = make([]string, 0, min(count, _binaryPrealloc))
+            numKeys += count
+            leaves   = append(leaves, len(nodes))
+        } else { //parent node: partition point & child links
+            for i := range n.CENTER   { n.CENTER[i]   = math.Float64frombits(le.Uint64(record[16 + 8*i:])) }
+            for i := range n.CHILDREN {
+                n.CHILDREN[i] = int(int32(le.Uint32(record[40 + 4*i:])))
+                if n.CHILDREN[i] == -1 && header.FLAGS & _binarySparse != 0 { continue } //empty octant left out
+                if n.CHILDREN[i] < 0 || int64(n.CHILDREN[i]) >= header.SIZE {
+                    return counter.n, fail(ErrBadFile, fmt.Sprintf("node %d links octant %d to node %d, out of range",
+                                                                   k, i, n.CHILDREN[i]))
+                }
+            }
+        }
+        nodes = append(nodes, n)
+    }
+    if numKeys != header.KEYS { return counter.n, fail(ErrBadFile, "the key table doesn't match the leaf nodes") }
+
+    var buffer []byte
+    for _, k := range leaves { //decode the key table, leaf by leaf
+        for j := 0; j < nodes[k].N; j++ {
+            length, err := binary.ReadUvarint(counter)
+            if err == nil && length > math.MaxInt32 { err = errors.New("key too long") }
+            if err != nil { return counter.n, fail(ErrBadFile, "reading the key table - " + err.Error()) }
+            buffer = buffer[:0]
+            for size := int(length); len(buffer) < size; { //grow the buffer by bounded chunks as the bytes arrive
+                start := len(buffer)
+                buffer = append(buffer, make([]byte, min(size - start, _binaryKeyChunk))...)
+                if _, err := io.ReadFull(counter, buffer[start:]); err != nil {
+                    return counter.n, fail(ErrBadFile, "reading the key table - " + err.Error())
+                }
+            }
+            nodes[k].KEYS = append(nodes[k].KEYS, string(buffer))
+        }
+    }
+    if header.FLAGS & _binaryPoints != 0 { //decode the coordinate table, leaf by leaf
+        var coords [24]byte
+        for _, k := range leaves {
+            nodes[k].PTS = make([]DataCoords, len(nodes[k].KEYS))
+            for j := range nodes[k].PTS {
+                if _, err := io.ReadFull(counter, coords[:]); err != nil {
+                    return counter.n, fail(ErrBadFile, "reading the coordinate table - " + err.Error())
+                }
+                for i := range nodes[k].PTS[j] { nodes[k].PTS[j][i] = math.Float64frombits(le.Uint64(coords[8*i:])) }
+            }
+        }
+    }
+
+    meta := jsonOctree{ HOW:    method.String(),
+                        STOP:   int(header.STOP),
+                        TIME:   time.Duration(header.TIME),
+                        CONFIG: &Config{ TOL:           header.TOL,
+                                         MAXITERATIONS: int(header.MAXITERATIONS),
+                                         MAXDEPTH:      int(header.MAXDEPTH),
+                                         MINCELLSIZE:   header.MINCELLSIZE,
+                                         PADCUBE:       header.FLAGS & _binaryPadCube != 0,
+                                         SPARSE:        header.FLAGS & _binarySparse != 0 } }
+    if header.FLAGS & _binaryCube != 0 { meta.CUBE = &header.CUBE }
+    return counter.n, t.restore(&meta, nodes)
+} //end func (*Octree).ReadBinary
+func (t *Octree) ReadFrom(reader io.Reader) (int64, error) {
+/*         Purpose : Replaces the octree with one imported from a JSON stream, one node at a time.
+ *       Arguments : reader = source of the JSON data, e.g., an HTTP request body.
+ *         Returns : the number of bytes consumed, and nil or an *Error.
+ * Externals -  In : Config, jsonNode, jsonOctree, node
+ * Externals - Out : None.
+ *       Functions : fail, restore
+ *         Remarks : The nodes are decoded one at a time so that only the resulting octree is held in memory. The meta data
+ *                   may precede or follow them.
+ *                   The partitioning method must be a built-in one or have been added with RegisterMethod beforehand.
+ *                   The build configuration is restored from the meta data, v1 files getting the defaults.
+ *                   A node is a leaf according to its flag or, for v1 files which lack it, if its point count doesn't
+ *                   exceed the termination criterion or it has no children.
+ *                   The point coordinates of the leaf nodes are restored if the stream holds them.
+ *                   The nodes must have their ids in order, as many as the meta data's count, and the parents must have a
+ *                   center & children. The structure is then validated as by restore.
+ *                   The octree is left unchanged should the stream be invalid. It implements io.ReaderFrom.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    var(
+        centerless []int                              //indices of the would-be parent nodes lacking a center
+        decoder    = json.NewDecoder(reader)
+        fields     = make(map[string]json.RawMessage) //meta data
+        infer      []int                              //indices of the v1 nodes whose type depends on the criterion
+        jsonIn     jsonOctree
+        nodes      []node
+    )
+
+    if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+        return decoder.InputOffset(), fail(ErrBadFile, "the input is not a JSON object")
+    }
+    for decoder.More() {
+        token, err := decoder.Token() //get the field name
+        if err != nil { return decoder.InputOffset(), fail(ErrBadFile, "decoder.Token - " + err.Error()) }
+        if name, _ := token.(string); !strings.EqualFold(name, "octree") { //set aside the meta data
+            var value json.RawMessage
+            if err = decoder.Decode(&value); err != nil {
+                return decoder.InputOffset(), fail(ErrBadFile, "decoder.Decode - " + err.Error())
+            }
+            fields[name] = value
+            continue
+        }
+        if token, err = decoder.Token(); err != nil || token != json.Delim('[') {
+            return decoder.InputOffset(), fail(ErrBadFile, "the octree data is not a JSON array")
+        }
+        for decoder.More() { //decode the nodes one at a time
+            var v jsonNode
+            if err = decoder.Decode(&v); err != nil {
+                return decoder.InputOffset(), fail(ErrBadFile, "decoder.Decode - " + err.Error())
+            }
+            if v.ID != len(nodes) {
+                msg := fmt.Sprintf("node %d has the id %d, out of order", len(nodes), v.ID)
+                return decoder.InputOffset(), fail(ErrBadFile, msg)
+            }
+            n := node{ N: v.N, KEYS: []string(v.KEYS), PTS: v.PTS, LEAF: v.CHILDREN == nil }
+            if v.CENTER   != nil { n.CENTER   = *(v.CENTER) }
+            if v.CHILDREN != nil { n.CHILDREN = *(v.CHILDREN) }
+            if v.LEAF != nil { n.LEAF = *(v.LEAF)
+            } else if !n.LEAF { infer = append(infer, len(nodes)) }
+            if !n.LEAF && v.CHILDREN == nil {
+                return decoder.InputOffset(), fail(ErrBadFile, fmt.Sprintf("node %d is a parent without children", len(nodes)))
+            }
+            if !n.LEAF && v.CENTER == nil { centerless = append(centerless, len(nodes)) }
+            nodes = append(nodes, n)
+        }
+        if _, err = decoder.Token(); err != nil { return decoder.InputOffset(), fail(ErrBadFile, "decoder.Token - " + err.Error()) }
+    }
+    if _, err := decoder.Token(); err != nil { return decoder.InputOffset(), fail(ErrBadFile, "decoder.Token - " + err.Error()) }
+    header, err := json.Marshal(fields) //decode the meta data
+    if err == nil { err = json.Unmarshal(header, &jsonIn) }
+    if err != nil { return decoder.InputOffset(), fail(ErrBadFile, "json.Unmarshal - " + err.Error()) }
+
+    for _, k := range infer { nodes[k].LEAF = !(nodes[k].N > jsonIn.STOP) } //infer the node type for v1 files
+    for _, k := range centerless {
+        if !nodes[k].LEAF {
+            return decoder.InputOffset(), fail(ErrBadFile, fmt.Sprintf("node %d is a parent without a center", k))
+        }
+    }
+    if jsonIn.SIZE != len(nodes) {
+        msg := fmt.Sprintf("the node count %d doesn't match the %d nodes", jsonIn.SIZE, len(nodes))
+        return decoder.InputOffset(), fail(ErrBadFile, msg)
+    }
+    return decoder.InputOffset(), t.restore(&jsonIn, nodes)
+} //end func (*Octree).ReadFrom
+func (t *Octree) Summarize(output ...string) error {
 /*         Purpose : Outputs the meta data and various statistics regarding the octree to a specified file or Stdout.
  *       Arguments : output[0] = optional output filename; the default is os.Stdout
- *         Returns : None.
- * Externals -  In : _stats
+ *         Returns : nil or an *Error.
+ * Externals -  In : None.
  * Externals - Out : None.
- *       Functions : halt
+ *       Functions : fail
  *         Remarks : None.
  *         History : v1.0.0 - October 26, 2016 - Original release.
+ *                   v2.0.0 - October 16, 2026 - Method of the Octree type.
  */
-    if _stats.SIZE == 0 { halt("there's no octree to summarize") }
+    if t.isEmpty() { return fail(ErrEmptyTree, "there's no octree to summarize") }
+    if t.stale     { t.calcStats() }
 
     const summary = `
 The octree contains {{.OCTREELEN}} nodes:
- - {{.NUMPARENTS}} are parents,
- - {{.NUMLEAVES}} are leaf nodes of which {{.NUMEMPTY}} are empty.
+ - {{.NUMPARENTS}} are parents{{if ne .NUMABSENT "0"}}, {{.NUMABSENT}} of whose octants are empty and left out{{end}},
+ - {{.NUMLEAVES}} are leaf nodes of which {{.NUMEMPTY}} are empty and {{.NUMOVER}} exceed the termination criterion.
 
 Partitioning, with the method "{{.HOW}}" and a termination criterion of {{.TERMINAL_N}} points,
 resulted in {{.MINPTS}} to {{.MAXPTS}} data points per leaf node
 with a mean of {{.MU}} and a population standard deviation of {{.SIGMA}}.
 
-Execution time was {{.TIME}}.
+{{with .MEDIAN}}{{if .NODES}}The geometric median of {{comma .NODES}} nodes took {{comma .ITERATIONS}} Weiszfeld iterations, at most {{comma .WORST}} for a node,
+with a largest residual of {{printf "%.3g" .RESIDUAL}} at termination. Aitken extrapolations accepted/rejected were
+{{index .ACCEPTED 0}}/{{index .REJECTED 0}} along x, {{index .ACCEPTED 1}}/{{index .REJECTED 1}} along y and {{index .ACCEPTED 2}}/{{index .REJECTED 2}} along z{{if .FALLBACKS}}, and {{comma .FALLBACKS}} nodes fell back on their XYZ medians{{end}}.
+
+{{end}}{{end}}Execution time was {{.TIME}}.
 
 `
     var(
@@ -305,53 +1261,347 @@ Execution time was {{.TIME}}.
             writer = os.Stdout
         case 1:
             writer, err = os.Create(output[0])
-            if err != nil { halt("os.Create - " + err.Error()) }
+            if err != nil { return fail(err, "os.Create - " + err.Error()) }
             defer writer.Close()
         default:
-            halt("too many arguments specified")
+            return fail(ErrBadArgument, "too many arguments specified")
     }
 
-    err = template.Must(template.New("").Parse(summary)).Execute(writer, _stats)
-    if err != nil { halt("executing template - " + err.Error()) }
+    funcs := template.FuncMap{ "comma": func(n int) string { return humanize.Comma(int64(n)) } }
+    err    = template.Must(template.New("").Funcs(funcs).Parse(summary)).Execute(writer, t.stats)
+    if err != nil { return fail(err, "executing template - " + err.Error()) }
 
     if writer != os.Stdout {
-        if err = writer.Sync(); err != nil { halt("writer.Sync - " + err.Error()) }
-        if err = writer.Close(); err != nil { halt("writer.Close - " + err.Error()) }
+        if err = writer.Sync(); err != nil { return fail(err, "writer.Sync - " + err.Error()) }
+        if err = writer.Close(); err != nil { return fail(err, "writer.Close - " + err.Error()) }
     }
-    return
-} //end func Summarize
+    return nil
+} //end func (*Octree).Summarize
+func (t *Octree) WithinRadius(center DataCoords, r float64) ([]string, error) {
+/*         Purpose : Searches the octree for the data points lying within a given distance of a specified point.
+ *       Arguments : center = R^3 coordinates of the sphere's center,
+ *                   r      = radius of the sphere (>=0).
+ *         Returns : the data-point identifiers, or an *Error.
+ * Externals -  In : DataCoords
+ * Externals - Out : None.
+ *       Functions : collectKeys, fail, (cellBounds).child, (cellBounds).distance2, (cellBounds).farDistance2, rootBounds
+ *         Remarks : The octants are bounded by the partition points of their ancestors. An octant lying outside the sphere
+ *                   is skipped whereas one lying inside it is accepted whole. Points at distance r are included.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty() { return nil, fail(ErrEmptyTree, "there's no octree to query") }
+    if !(r >= 0.)  { return nil, fail(ErrBadArgument, fmt.Sprintf("invalid radius '%g'", r)) }
+
+    var(
+        keys  []string
+        r2    = r * r
+        stack = []searchItem{ { NODE: 0, BOUNDS: rootBounds() } }
+    )
+    for len(stack) > 0 {
+        item    := stack[len(stack)-1]
+        stack    = stack[:len(stack)-1]
+        thisNode := &t.nodes[item.NODE]
+        switch {
+            case thisNode.N == 0 || item.BOUNDS.distance2(&center) > r2: //octant outside the sphere
+            case item.BOUNDS.farDistance2(&center) <= r2:                //octant inside the sphere
+                keys = t.collectKeys(item.NODE, keys)
+            case !thisNode.LEAF:                                         //parent node straddling the sphere
+                for octant, childIdx := range thisNode.CHILDREN {
+                    if childIdx < 0 { continue } //empty octant left out
+                    stack = append(stack, searchItem{ NODE: childIdx, BOUNDS: item.BOUNDS.child(&thisNode.CENTER, octant) })
+                }
+            default:                                                     //leaf node straddling the sphere
+                if len(thisNode.PTS) != thisNode.N {
+                    return nil, fail(ErrNoCoords, "the leaf nodes lack point coordinates")
+                }
+                for i, key := range thisNode.KEYS {
+                    if distance2(&center, &(thisNode.PTS[i])) <= r2 { keys = append(keys, key) }
+                }
+        }
+    }
+    return keys, nil
+} //end func (*Octree).WithinRadius
+func (t *Octree) WriteBinary(writer io.Writer, points bool) (int64, error) {
+/*         Purpose : Exports the octree and its meta data to a binary stream, with or without the point coordinates.
+ *       Arguments : writer = destination of the binary data, preferably buffered,
+ *                   points = boolean flag for embedding the point coordinates of the leaf nodes.
+ *         Returns : the number of bytes written, and nil or an *Error.
+ * Externals -  In : binHeader
+ * Externals - Out : None.
+ *       Functions : fail, hasCoords
+ *         Remarks : The format, version 1, is little-endian throughout and made up of:
+ *                   - a 128-byte header: the signature "OCTB", the uint32 version, uint32 flags for a root cube (1),
+ *                     PADCUBE (2), SPARSE (4) & point coordinates (8), the uint32 byte length of the method name, the
+ *                     int64 termination criterion, build time in nanoseconds, node count & key count, the float64 TOL, the int64
+ *                     MAXITERATIONS & MAXDEPTH, the float64 MINCELLSIZE and the 6 float64 MIN & MAX root-cube ordinates;
+ *                   - the method name;
+ *                   - a 72-byte record per node, in the octree's order: the int64 point count N, the uint32 number of
+ *                     keys of a leaf, uint32 flags for a leaf (1), the 3 float64 CENTER ordinates and the 8 int32
+ *                     CHILDREN links of a parent, the unused fields being zero;
+ *                   - the key table: each leaf's keys in turn, each as its byte length in the unsigned varint encoding
+ *                     of encoding/binary followed by its bytes;
+ *                   - with the point coordinates, the coordinate table: the 3 float64 ordinates of each key in turn.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty() { return 0, fail(ErrEmptyTree, "there's no octree to export") }
+    if len(t.nodes) > math.MaxInt32 { return 0, fail(ErrBadArgument, "the octree has too many nodes for the binary format") }
+    if points && !t.hasCoords() { return 0, fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
+
+    var(
+        buffer  []byte
+        counter = &countWriter{ writer: writer }
+        header  = binHeader{ VERSION:       _binaryVersion,
+                             METHODLEN:     uint32(len(t.stats.HOW)),
+                             STOP:          int64(t.stats.STOP),
+                             TIME:          int64(t.stats.TIME),
+                             SIZE:          int64(len(t.nodes)),
+                             TOL:           t.config.TOL,
+                             MAXITERATIONS: int64(t.config.MAXITERATIONS),
+                             MAXDEPTH:      int64(t.config.MAXDEPTH),
+                             MINCELLSIZE:   t.config.MINCELLSIZE }
+        le      = binary.LittleEndian
+        record  [_binaryNodeLen]byte
+    )
+    copy(header.MAGIC[:], _binaryMagic)
+    if t.stats.CUBE != nil {
+        header.FLAGS |= _binaryCube
+        header.CUBE   = [2]DataCoords{ t.stats.CUBE.MIN, t.stats.CUBE.MAX }
+    }
+    if t.config.PADCUBE { header.FLAGS |= _binaryPadCube }
+    if t.config.SPARSE  { header.FLAGS |= _binarySparse }
+    if points           { header.FLAGS |= _binaryPoints }
+    for k := range t.nodes {
+        if t.nodes[k].LEAF { header.KEYS += int64(len(t.nodes[k].KEYS)) }
+    }
+    if err := binary.Write(counter, le, &header); err != nil { return counter.n, fail(err, "binary.Write - " + err.Error()) }
+    if _, err := io.WriteString(counter, t.stats.HOW); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+
+    for k := range t.nodes { //encode the fixed-size node records
+        v     := &(t.nodes[k])
+        record = [_binaryNodeLen]byte{}
+        le.PutUint64(record[0:], uint64(v.N))
+        if v.LEAF { //leaf node
+            le.PutUint32(record[8:], uint32(len(v.KEYS)))
+            le.PutUint32(record[12:], _binaryLeaf)
+        } else {    //parent node
+            for i, x := range v.CENTER   { le.PutUint64(record[16 + 8*i:], math.Float64bits(x)) }
+            for i, c := range v.CHILDREN { le.PutUint32(record[40 + 4*i:], uint32(int32(c))) }
+        }
+        if _, err := counter.Write(record[:]); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+    }
+    for k := range t.nodes { //encode the key table
+        if !t.nodes[k].LEAF { continue }
+        for _, key := range t.nodes[k].KEYS {
+            buffer = binary.AppendUvarint(buffer[:0], uint64(len(key)))
+            buffer = append(buffer, key...)
+            if _, err := counter.Write(buffer); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+        }
+    }
+    if points { //encode the coordinate table
+        for k := range t.nodes {
+            if !t.nodes[k].LEAF { continue }
+            for _, pt := range t.nodes[k].PTS {
+                buffer = buffer[:0]
+                for _, x := range pt { buffer = le.AppendUint64(buffer, math.Float64bits(x)) }
+                if _, err := counter.Write(buffer); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+            }
+        }
+    }
+    return counter.n, nil
+} //end func (*Octree).WriteBinary
+func (t *Octree) WriteJSON(writer io.Writer, compact, points bool) (int64, error) {
+/*         Purpose : Exports the octree and its meta data to a JSON stream, one node at a time, with or without newlines and
+ *                   indentations, and with or without the point coordinates.
+ *       Arguments : writer  = destination of the JSON data, e.g., an HTTP response or an upload stream,
+ *                   compact = boolean flag for compact mode,
+ *                   points  = boolean flag for embedding the point coordinates of the leaf nodes.
+ *         Returns : the number of bytes written, and nil or an *Error.
+ * Externals -  In : jsonNode, jsonOctree
+ * Externals - Out : None.
+ *       Functions : fail, hasCoords
+ *         Remarks : The output is the same as that of marshalling the whole octree at once, written without buffering;
+ *                   wrap the writer in a bufio.Writer for small writes to be coalesced.
+ *                   The point coordinates of a leaf are written as "pts", an array of [x,y,z] arrays in the order of its
+ *                   keys.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    if t.isEmpty() { return 0, fail(ErrEmptyTree, "there's no octree to export") }
+    if points && !t.hasCoords() { return 0, fail(ErrNoCoords, "the leaf nodes lack point coordinates") }
+
+    var(
+        buffer  bytes.Buffer
+        counter = &countWriter{ writer: writer }
+        head    []byte
+        err     error
+        lead    = [2]string{ "", "," }     //separators before the first & the other nodes
+        tail    = "]}"                     //closing of the node array & the octree
+    )
+    jsonData := jsonOctree{ HOW:    t.stats.HOW,
+                            STOP:   t.stats.STOP,
+                            TIME:   t.stats.TIME,
+                            SIZE:   t.stats.SIZE,
+                            CONFIG: &(t.config),
+                            OCTREE: []jsonNode{} }
+    if t.stats.CUBE != nil { jsonData.CUBE = &[2]DataCoords{ t.stats.CUBE.MIN, t.stats.CUBE.MAX } }
+    if !compact { head, err = json.MarshalIndent(jsonData, "", " ")
+                  lead, tail = [2]string{ "\n  ", ",\n  " }, "\n ]\n}"
+    } else      { head, err = json.Marshal(jsonData) }
+    if err != nil { return 0, fail(err, "Marshal/MarshalIndent - " + err.Error()) }
+    head = head[:bytes.LastIndexByte(head, '[') + 1] //open the node array, the last field
+    if _, err = counter.Write(head); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+
+    encoder := json.NewEncoder(&buffer)
+    if !compact { encoder.SetIndent("  ", " ") }
+    for k := range t.nodes {
+        v    := &(t.nodes[k])
+        data := jsonNode{ ID: k, N: v.N, LEAF: &(v.LEAF) }
+        if !v.LEAF { data.CENTER, data.CHILDREN = &(v.CENTER), &(v.CHILDREN) //parent node
+        } else     { data.KEYS = jsonKeys(v.KEYS) }                          //leaf node
+        if v.LEAF && points { data.PTS = v.PTS }
+        buffer.Reset()
+        if k == 0 { buffer.WriteString(lead[0])
+        } else    { buffer.WriteString(lead[1]) }
+        if err = encoder.Encode(data); err != nil { return counter.n, fail(err, "encoder.Encode - " + err.Error()) }
+        buffer.Truncate(buffer.Len() - 1) //drop the encoder's newline
+        if _, err = counter.Write(buffer.Bytes()); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+    }
+    if _, err = io.WriteString(counter, tail); err != nil { return counter.n, fail(err, "writer.Write - " + err.Error()) }
+    return counter.n, nil
+} //end func (*Octree).WriteJSON
+func (t *Octree) WriteTo(writer io.Writer) (int64, error) {
+/*         Purpose : Exports the octree and its meta data to a JSON stream in compact mode, one node at a time.
+ *       Arguments : writer = destination of the JSON data, e.g., an HTTP response or an upload stream.
+ *         Returns : the number of bytes written, and nil or an *Error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : (*Octree).WriteJSON
+ *         Remarks : The output is that of WriteJSON in compact mode without the point coordinates. It implements
+ *                   io.WriterTo.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return t.WriteJSON(writer, true, false)
+} //end func (*Octree).WriteTo
+////Error methods
+func (e *Error) Error() string {
+/*         Purpose : Formats the error as the name of the failing function followed by a description of the failure.
+ *       Arguments : None.
+ *         Returns : the error message.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : None.
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return fmt.Sprintf("%s: %s", e.Op, e.Msg)
+} //end func (*Error).Error
+func (e *Error) Unwrap() error {
+/*         Purpose : Exposes the underlying sentinel or system error to errors.Is and errors.As.
+ *       Arguments : None.
+ *         Returns : the underlying error.
+ * Externals -  In : None.
+ * Externals - Out : None.
+ *       Functions : None.
+ *         Remarks : None.
+ *         History : v2.0.0 - October 16, 2026 - Original release.
+ */
+    return e.Err
+} //end func (*Error).Unwrap
 //Private ----------------------------------------------------------------------------------------------------------------------
 type (
-    builderFn func(refPoints *DataSet)                         //octree builder
-    centerFn  func(refPoints *DataSet) DataCoords              //center calculator
+    binHeader struct {                                         //binary structure for the octree meta data:
+        MAGIC         [4]byte                                  // format signature
+        VERSION       uint32                                   // format version
+        FLAGS         uint32                                   // flags for a root cube, PADCUBE & SPARSE
+        METHODLEN     uint32                                   // byte length of the method name following the header
+        STOP          int64                                    // termination criterion
+        TIME          int64                                    // build time in nanoseconds
+        SIZE          int64                                    // number of node records
+        KEYS          int64                                    // number of keys in the key table
+        TOL           float64                                  // build configuration
+        MAXITERATIONS int64
+        MAXDEPTH      int64
+        MINCELLSIZE   float64
+        CUBE          [2]DataCoords                            // root cube of the regular method, zero otherwise
+    }
+    builder struct {                                           //octree builder:
+        calcCenter  centerFn                                   // center calculator
+        ctx         context.Context                            // context whose cancellation stops the build
+        maxDepth    int                                        // max depth of a node, 0 for no limit
+        minCellSize float64                                    // min extent of a node's points for splitting
+        regular     bool                                       // flag for the regular method splitting cubes at their centers
+        sparse      bool                                       // flag for leaving out the empty octants
+        terminal_N  int                                        // termination criterion
+        tokens      chan struct{}                              // worker-pool tokens for concurrent subtree builds
+        mu          sync.Mutex                                 // guard for the progress counts & callback
+        placed      int                                        // number of data points placed in leaf nodes
+        created     int                                        // number of nodes created
+        total       int                                        // total number of data points
+        report      func(Progress)                             // progress callback, nil for none
+        start       time.Time                                  // start time of the build
+    }
+    centerFn        func(pts []DataCoords, refBounds *cellBounds) (DataCoords, error) //center calculator
 
+    countReader struct {                                       //reader counting the bytes read through it:
+        reader      io.Reader                                  // underlying reader
+        n           int64                                      // number of bytes read
+    }
+    countWriter struct {                                       //writer counting the bytes written through it:
+        writer      io.Writer                                  // underlying writer
+        n           int64                                      // number of bytes written
+    }
     jsonNode struct {                                          //JSON structure for an octree node:
-        ID          int           `json:"id"`                  // node meta data
-        N           int           `json:"N"`                   // node data
-        CENTER      *DataCoords   `json:"center,omitempty"`
-        CHILDREN    *nodeLinks    `json:"children,omitempty"`
-        KEYS        string        `json:"keys,omitempty"`
+        ID          int            `json:"id"`                 // node meta data
+        N           int            `json:"N"`                  // node data
+        CENTER      *DataCoords    `json:"center,omitempty"`
+        CHILDREN    *nodeLinks     `json:"children,omitempty"`
+        KEYS        jsonKeys       `json:"keys,omitempty"`
+        PTS         []DataCoords   `json:"pts,omitempty"`
+        LEAF        *bool          `json:"leaf,omitempty"`     // node type, missing from v1 files
     }
+    jsonKeys        []string                                   //JSON array of leaf keys, also read from a legacy CSV string
     jsonOctree struct {                                        //JSON structure for the octree:
-        HOW         string        `json:"method"`              // octree meta data
-        STOP        int           `json:"terminal_N"`
-        TIME        time.Duration `json:"time"`
-        SIZE        int           `json:"nodes"`
-        OCTREE      []jsonNode    `json:"octree"`              // octree data
+        HOW         string         `json:"method"`             // octree meta data
+        STOP        int            `json:"terminal_N"`
+        TIME        time.Duration  `json:"time"`
+        SIZE        int            `json:"nodes"`
+        CUBE        *[2]DataCoords `json:"cube,omitempty"`
+        CONFIG      *Config        `json:"config,omitempty"`
+        OCTREE      []jsonNode     `json:"octree"`             // octree data
     }
     node struct {                                              //octree node structure:
         N           int                                        // number of data points associated with the node
         CENTER      DataCoords                                 // array for the parent's partition point coordinates
         CHILDREN    nodeLinks                                  // array of links for the corresponding child nodes
-        KEYS        string                                     // CSV of identifiers for the data points associated with a leaf
+        KEYS        []string                                   // identifiers for the data points associated with a leaf
+        PTS         []DataCoords                               // coordinates of a leaf's data points, in the order of KEYS
+        LEAF        bool                                       // flag for a leaf node
+    }
+    medianDiag struct {                                        //geometric-median diagnostics shared by concurrent builds:
+        mu          sync.Mutex                                 // guard for the diagnostics
+        STATS       MedianStats                                // diagnostics
     }
     nodeLinks       [8]int                                     //array of links to child nodes
+    cellBounds struct {                                        //bounds of an octant implied by its ancestors' partition points:
+        MIN         DataCoords                                 // lower bounds
+        MAX         DataCoords                                 // upper bounds (inclusive)
+        OPEN        [3]bool                                    // flags for exclusive lower bounds
+    }
+    searchItem struct {                                        //best-first search queue item:
+        DIST2       float64                                    // squared distance to the query point
+        NODE        int                                        // octree index of an octant, or -1 for a data point
+        BOUNDS      cellBounds                                 // bounds of the octant
+        KEY         string                                     // identifier of the data point
+    }
+    searchQueue     []searchItem                               //priority queue for best-first searches
     statistics struct {                                        //octree meta data & statistics:
-        //set by funcs Make & Import:
+        //set by funcs New & Import:
         HOW         string                                     // partitioning method
         SIZE        int                                        // number of octree nodes
         STOP        int                                        // stopping criterion
         TIME        time.Duration                              // execution time
+        CUBE        *cellBounds                                // root cube of the regular method, nil otherwise
+        //set by funcs New & Insert
+        MEDIAN      MedianStats                                // geometric-median diagnostics
         //set by calcStats
         LEAFCOUNTS  []int                                      // leaf point counts
         MINPTS      string                                     // smallest leaf point count
@@ -360,6 +1610,8 @@ type (
         NUMPTS      string                                     // number of data points
         NUMLEAVES   string                                     // number of leaf nodes
         NUMEMPTY    string                                     // number of leaf nodes with no points
+        NUMABSENT   string                                     // number of empty octants left out
+        NUMOVER     string                                     // number of leaf nodes exceeding the stopping criterion
         OCTREELEN   string                                     // number of octree nodes
         TERMINAL_N  string                                     // stopping criterion
         MU          string                                     // mean (mu) leaf point count
@@ -370,12 +1622,58 @@ type (
         XMAX        int                                        // largest leaf point count
     }
 )
-const _progressBarLen = 50
+const(
+    _binaryCube     = 1                                        //binary header flags
+    _binaryPadCube  = 2
+    _binarySparse   = 4
+    _binaryPoints   = 8
+    _binaryLeaf     = 1                                        //binary node flag
+    _binaryKeyChunk = 1 << 16                                  //max no of key bytes allocated ahead of reading them
+    _binaryMagic    = "OCTB"                                   //binary format signature
+    _binaryNodeLen  = 72                                       //byte length of a binary node record
+    _binaryPrealloc = 1 << 20                                  //max no of nodes or keys allocated ahead of reading them
+    _binaryVersion  = 1                                        //binary format version
+    _parallelGrain  = 4096                                     //min no of points for building a subtree concurrently
+    _progressBarLen = 50                                       //progress-bar length in characters
+)
 var (
-    _builder builderFn  //octree builder
-    _octree  []node     //octree as a slice of nodes
-    _stats   statistics //octree meta data & statistics
+    _default   *Octree                                         //default octree for the package-level functions
+    _defaultMu sync.RWMutex                                    //guard for the default octree
+    _methods   = make(map[string]func([]DataCoords) DataCoords) //custom partitioning methods keyed on their names
+    _methodsMu sync.RWMutex                                    //guard for the custom partitioning methods
 )
+////Files
+func importFile(file string) (*Octree, bool, error) {
+    //Imports an octree from a JSON or binary file, recognized by its signature, and tells whether it was binary.
+    if fi, err := os.Stat(file); (err != nil) || (fi.Size() == 0) {
+        return nil, false, fail(ErrBadFile, "the input file cannot be located or is empty")
+    }
+
+    input, err := os.Open(file) //open file for read
+    if err != nil { return nil, false, fail(err, "os.Open - " + err.Error()) }
+    defer input.Close()
+
+    reader   := bufio.NewReader(input)
+    t        := new(Octree)
+    magic, _ := reader.Peek(len(_binaryMagic))
+    isBinary := string(magic) == _binaryMagic
+    if isBinary { _, err = t.ReadBinary(reader)
+    } else      { _, err = t.ReadFrom(reader) }
+    if err != nil { return nil, isBinary, err }
+    return t, isBinary, nil
+} //end func importFile
+////JSON
+func (keys *jsonKeys) UnmarshalJSON(data []byte) error {
+    //Decodes the leaf keys from a JSON array or, for files written by v1, from a CSV string.
+    var csv string
+    if len(data) > 0 && data[0] == '"' {
+        if err := json.Unmarshal(data, &csv); err != nil { return err }
+        if csv == "" { *keys = nil
+        } else       { *keys = strings.Split(csv, ",") }
+        return nil
+    }
+    return json.Unmarshal(data, (*[]string)(keys))
+} //end func (*jsonKeys).UnmarshalJSON
 ////Octree build & query
 func assignOctant(refCenter, refPoint *DataCoords) (octant int) {
     //Establishes the rule for creating the octree and querying it for the associated points.
@@ -395,84 +1693,388 @@ func calcAitkenEstimate(e []DataCoords) (aitken DataCoords) {
     }
     return
 } //end func calcAitkenEstimate
-func calcWeiszfeldEstimate(refPoints *DataSet, refEstimate *DataCoords) (weiszfeld DataCoords) {
-    //Estimates the value of the geometric median in R^3 using Weiszfeld's fixed-point expression.
+func calcWeiszfeldEstimate(pts []DataCoords, refEstimate *DataCoords) (weiszfeld DataCoords) {
+    //Estimates the value of the geometric median in R^3 using Weiszfeld's fixed-point expression as modified by Vardi &
+    //Zhang, which remains defined when the estimate coincides with data points.
     var(
-        denom float64
-        num   [3]float64
+        coincident float64    //number of data points coinciding with the estimate
+        denom      float64
+        num        [3]float64
+        residual   [3]float64 //sum of the unit vectors from the estimate to the other data points
     )
-    for _, v := range *refPoints {
+    for _, v := range pts {
         metric := 0. // Euclidean distance of point to estimate
         for k := range num {
             diff := v[k] - (*refEstimate)[k]; metric += diff * diff
         }
+        if metric == 0. { coincident++; continue }
         metric  = math.Sqrt(metric)
         denom  += 1./metric
-        for k := range num { num[k] += v[k]/metric }
+        for k := range num {
+            num[k]      += v[k]/metric
+            residual[k] += (v[k] - (*refEstimate)[k])/metric
+        }
     }
+    if denom == 0. { return *refEstimate } //all the data points coincide with the estimate
     for k := range num { weiszfeld[k] = num[k] / denom }
+    if coincident == 0. { return }
+    //Pull the estimate back towards the coinciding data points in proportion to their number
+    ratio := coincident / math.Sqrt(residual[0]*residual[0] + residual[1]*residual[1] + residual[2]*residual[2])
+    if ratio >= 1. { return *refEstimate } //the estimate is the geometric median
+    for k := range num { weiszfeld[k] = (1. - ratio)*weiszfeld[k] + ratio*(*refEstimate)[k] }
     return
 } //end func calcWeiszfeldEstimate
-func makeBuilder(method string, termination int, numPts int) builderFn {
+func (b *builder) build(keys []string, pts []DataCoords, nodes []node, bounds cellBounds, depth int) ([]node, error) {
+    //Appends the subtree for the given data points, rooted at the given extent & depth, to a slice of nodes in top-down
+    //order.
+    //The points are partitioned in place into contiguous ranges, one per child, so that each leaf references a range.
+    //Child subtrees with enough points are built concurrently into slices of their own, as worker-pool tokens allow,
+    //and then spliced in order so that the layout is identical to that of a serial build.
     var(
-        calcCenter    = makeCalcCenter(method) //center calculator
-        current       int                      //progress-bar current count
-        masterNodeIdx int                      //master node index
-        terminal_N    = termination            //termination criterion
-        total         = numPts                 //progress-bar total count
+        async    bool                      //flag for child subtrees built concurrently
+        launched [8]bool                   //flags for the child subtrees built concurrently
+        numPts   = len(pts)                //number of data points
+        ranges   [9]int                    //child k holds the data points in [ranges[k],ranges[k+1])
+        subErrs  [8]error                  //errors for the child subtrees built separately
+        subtrees [8][]node                 //child subtrees built separately
+        wg       sync.WaitGroup
     )
-    return func(refPoints *DataSet) {
-            var(
-                childPts [8]DataSet        //data points in child nodes
-                numPts   = len(*refPoints) //number of data points
-            )
-            //Initialize
-            _octree                 = append(_octree, node{}) //add blank node to octree
-            thisNodeIdx            := masterNodeIdx           //set this node's index value
-            _octree[thisNodeIdx].N  = numPts                  //record node's point count
-            //Check for a leaf node
-            if numPts <= terminal_N {
-                i    := 0
-                keys := make([]string, numPts, numPts)
-                for k := range *refPoints {
-                    keys[i] = k
-                    i++
+    //Initialize
+    if err := b.ctx.Err(); err != nil { return nodes, fail(err, "build stopped - " + err.Error()) }
+    thisNodeIdx := len(nodes)                       //set this node's index value
+    nodes        = append(nodes, node{ N: numPts }) //add node to octree with its point count
+    //Check for a leaf node, possibly exceeding the termination criterion if too deep or too small to split
+    if numPts <= b.terminal_N || (b.maxDepth > 0 && depth >= b.maxDepth) || b.isSmall(pts) {
+        return b.makeLeaf(keys, pts, nodes, thisNodeIdx), nil
+    }
+    //Compute the partition point
+    center, err := b.calcCenter(pts, &bounds)
+    if err != nil { return nodes, err }
+    nodes[thisNodeIdx].CENTER = center
+    //Segregate the data points relative to the partition point
+    ranges = partition(&center, keys, pts)
+    //Check for a degenerate partition, e.g., duplicate points, which would otherwise recurse endlessly
+    for k := range subtrees {
+        if ranges[k+1] - ranges[k] == numPts && (!b.regular || extent(pts) == 0. || !bounds.splittable(&center)) {
+            return b.makeLeaf(keys, pts, nodes, thisNodeIdx), nil
+        }
+    }
+    b.progress(1, 0) //report the parent node
+    //Launch the child subtrees large enough to be built concurrently
+    for k := range subtrees {
+        lo, hi := ranges[k], ranges[k+1]
+        if hi - lo < _parallelGrain { continue }
+        select {
+            case b.tokens <- struct{}{}:
+                async, launched[k] = true, true
+                wg.Add(1)
+                go func(k int) {
+                    defer func() { <-b.tokens; wg.Done() }()
+                    subtrees[k], subErrs[k] = b.build(keys[lo:hi], pts[lo:hi], nil, bounds.child(&center, k), depth + 1)
+                }(k)
+            default:
+        }
+    }
+    //Create the child nodes
+    for k := range subtrees {
+        lo, hi := ranges[k], ranges[k+1]
+        switch {
+            case b.sparse && lo == hi: //leave out the empty octant
+                nodes[thisNodeIdx].CHILDREN[k] = -1
+            case !async:               //append directly
+                nodes[thisNodeIdx].CHILDREN[k] = len(nodes)
+                nodes, err = b.build(keys[lo:hi], pts[lo:hi], nodes, bounds.child(&center, k), depth + 1)
+                if err != nil { return nodes, err }
+            case !launched[k]:         //build separately while the others run
+                subtrees[k], subErrs[k] = b.build(keys[lo:hi], pts[lo:hi], nil, bounds.child(&center, k), depth + 1)
+        }
+    }
+    if async { //stitch the subtrees in order
+        wg.Wait()
+        for k := range subtrees {
+            if subErrs[k] != nil { return nodes, subErrs[k] }
+            if nodes[thisNodeIdx].CHILDREN[k] < 0 { continue }
+            nodes[thisNodeIdx].CHILDREN[k] = len(nodes)
+            nodes = b.splice(nodes, subtrees[k])
+        }
+    }
+    return nodes, nil
+} //end func (*builder).build
+func (b *builder) isSmall(pts []DataCoords) bool {
+    //Checks whether the data points span no more than the minimum cell size along every axis.
+    return b.minCellSize > 0. && extent(pts) <= b.minCellSize
+} //end func (*builder).isSmall
+func (b *builder) makeLeaf(keys []string, pts []DataCoords, nodes []node, nodeIdx int) []node {
+    //Turns a node into a leaf referencing the given range of data points, capped to keep appends off its neighbours'.
+    numPts        := len(pts)
+    nodes[nodeIdx] = node{ N: numPts, KEYS: keys[:numPts:numPts], PTS: pts[:numPts:numPts], LEAF: true }
+    b.progress(1, numPts)
+    return nodes
+} //end func (*builder).makeLeaf
+func (t *Octree) collapse(nodeIdx int) {
+    //Merges the subtree of a parent node into a single leaf node. The nodes of the subtree become unreachable.
+    var(
+        keys []string
+        pts  []DataCoords
+        walk func(nodeIdx int)
+    )
+    walk = func(nodeIdx int) {
+        thisNode := &t.nodes[nodeIdx]
+        switch {
+            case !thisNode.LEAF:
+                for _, childIdx := range thisNode.CHILDREN {
+                    if childIdx >= 0 { walk(childIdx) }
                 }
-                _octree[thisNodeIdx].KEYS  = strings.Join(keys, ",")
-                current                   += numPts
-                updateProgressBar("octree.Make:", current, total)
-                return
+            case thisNode.N > 0:
+                keys = append(keys, thisNode.KEYS...)
+                pts  = append(pts, thisNode.PTS...)
+        }
+    }
+    walk(nodeIdx)
+    t.nodes[nodeIdx] = node{ N: len(keys), KEYS: keys, PTS: pts, LEAF: true }
+    for _, key := range keys { t.index[key] = nodeIdx }
+} //end func (*Octree).collapse
+func (t *Octree) compact() {
+    //Drops the nodes no longer reachable from the root, renumbering the others in top-down order. The key index & parent
+    //links follow.
+    var(
+        newIdx = make([]int, len(t.nodes))  //new indices of the reachable nodes
+        nodes  = make([]node, 0, len(t.nodes))
+        visit  func(nodeIdx int)
+    )
+    visit = func(nodeIdx int) {
+        thisNodeIdx    := len(nodes)
+        newIdx[nodeIdx] = thisNodeIdx
+        nodes           = append(nodes, t.nodes[nodeIdx])
+        if nodes[thisNodeIdx].LEAF { return }
+        for k, childIdx := range t.nodes[nodeIdx].CHILDREN {
+            if childIdx < 0 { continue } //empty octant left out
+            nodes[thisNodeIdx].CHILDREN[k] = len(nodes)
+            visit(childIdx)
+        }
+    }
+    visit(0)
+    for key, nodeIdx := range t.index { t.index[key] = newIdx[nodeIdx] }
+    t.nodes = nodes
+    t.linkParents()
+} //end func (*Octree).compact
+func (cfg Config) resolve() (Config, error) {
+    //Validates a build configuration and fills in the defaults for its zero fields.
+    switch {
+        case !(cfg.TERMINAL_N > 1):
+            return cfg, fail(ErrBadArgument, fmt.Sprintf("invalid termination criterion '%d'", cfg.TERMINAL_N))
+        case cfg.TOL < 0. || math.IsNaN(cfg.TOL):
+            return cfg, fail(ErrBadArgument, fmt.Sprintf("invalid convergence tolerance '%g'", cfg.TOL))
+        case cfg.MAXITERATIONS < 0:
+            return cfg, fail(ErrBadArgument, fmt.Sprintf("invalid maximum number of iterations '%d'", cfg.MAXITERATIONS))
+        case cfg.MAXDEPTH < 0:
+            return cfg, fail(ErrBadArgument, fmt.Sprintf("invalid maximum depth '%d'", cfg.MAXDEPTH))
+        case cfg.MINCELLSIZE < 0. || math.IsNaN(cfg.MINCELLSIZE):
+            return cfg, fail(ErrBadArgument, fmt.Sprintf("invalid minimum cell size '%g'", cfg.MINCELLSIZE))
+        case cfg.WORKERS < 0:
+            return cfg, fail(ErrBadArgument, fmt.Sprintf("invalid number of workers '%d'", cfg.WORKERS))
+    }
+    if cfg.TOL == 0.          { cfg.TOL = 1.0E-12 }
+    if cfg.MAXITERATIONS == 0 { cfg.MAXITERATIONS = 1000 }
+    if cfg.WORKERS == 0       { cfg.WORKERS = runtime.NumCPU() }
+    return cfg, nil
+} //end func (Config).resolve
+func (t *Octree) restore(refMeta *jsonOctree, nodes []node) error {
+    //Replaces the octree with imported nodes & meta data, restoring the build configuration, v1 files getting the
+    //defaults, resolving the partitioning method and validating the nodes & their point coordinates, if any. The octree
+    //is left unchanged should any fail.
+    var(
+        cfg   Config
+        err   error
+        stats statistics
+    )
+    if refMeta.CONFIG != nil { cfg = *refMeta.CONFIG }
+    cfg.METHOD, cfg.TERMINAL_N = refMeta.HOW, refMeta.STOP
+    if cfg, err = cfg.resolve(); err != nil { return err }
+    if _, err = makeCalcCenter(cfg, nil); err != nil { return err }
+    if err = validateNodes(nodes, cfg.SPARSE); err != nil { return err }
+    if err = validatePoints(nodes); err != nil { return err }
+
+    stats.HOW  = refMeta.HOW
+    stats.SIZE = len(nodes)
+    stats.STOP = refMeta.STOP
+    stats.TIME = refMeta.TIME
+    if refMeta.CUBE != nil { stats.CUBE = &cellBounds{ MIN: refMeta.CUBE[0], MAX: refMeta.CUBE[1] } }
+    for k := range nodes {
+        if nodes[k].LEAF { nodes[k].CENTER, nodes[k].CHILDREN = DataCoords{}, nodeLinks{} //leaf node
+        } else           { nodes[k].KEYS, nodes[k].PTS = nil, nil }                       //parent node
+    }
+
+    t.config, t.nodes, t.stats, t.index, t.parents, t.stale = cfg, nodes, stats, nil, nil, false
+    t.median.STATS = MedianStats{}
+    t.calcStats()
+    return nil
+} //end func (*Octree).restore
+func validateNodes(nodes []node, sparse bool) error {
+    //Checks that imported nodes form an octree rooted at the first one: every other node is the child of exactly one
+    //parent listed before it, which rules out cycles, the point counts of a parent's children add up to its own, and
+    //a leaf has as many keys, and point coordinates if any, as its non-negative count. Empty octants may be left out,
+    //with -1 links, in a sparse octree only.
+    //The error names the first offending node.
+    if len(nodes) == 0 { return fail(ErrBadFile, "the octree has no nodes") }
+    linked := make([]bool, len(nodes)) //flags for the nodes linked from a parent
+    for k := range nodes {
+        v := &nodes[k]
+        if v.N < 0 { return fail(ErrBadFile, fmt.Sprintf("node %d has a negative count of %d", k, v.N)) }
+        if v.LEAF {
+            if len(v.KEYS) != v.N {
+                return fail(ErrBadFile, fmt.Sprintf("node %d is a leaf with %d keys for a count of %d", k, len(v.KEYS), v.N))
+            }
+            if v.PTS != nil && len(v.PTS) != v.N {
+                return fail(ErrBadFile, fmt.Sprintf("node %d is a leaf with %d points for a count of %d", k, len(v.PTS), v.N))
             }
-            //Compute the partition point
-            center := calcCenter(refPoints)
-            _octree[thisNodeIdx].CENTER = center
-            //Segregate the data points relative to the partition point
-            for k := range childPts { childPts[k] = make(DataSet) }
-            for k, v := range *refPoints { childPts[assignOctant(&center,&v)][k] = v }
-            //Create the child nodes
-            for k := range childPts {
-                masterNodeIdx++
-                _octree[thisNodeIdx].CHILDREN[k] = masterNodeIdx
-                _builder(&childPts[k])
+            continue
+        }
+        sum := 0
+        for octant, childIdx := range v.CHILDREN {
+            switch {
+                case childIdx == -1 && sparse: //empty octant left out
+                    continue
+                case childIdx < 0 || childIdx >= len(nodes):
+                    return fail(ErrBadFile, fmt.Sprintf("node %d links octant %d to node %d, out of range",
+                                                        k, octant, childIdx))
+                case childIdx <= k:
+                    return fail(ErrBadFile, fmt.Sprintf("node %d links octant %d back to node %d", k, octant, childIdx))
+                case linked[childIdx]:
+                    return fail(ErrBadFile, fmt.Sprintf("node %d links octant %d to node %d, already linked",
+                                                        k, octant, childIdx))
             }
-           }
+            linked[childIdx] = true
+            sum             += nodes[childIdx].N
+        }
+        if sum != v.N {
+            return fail(ErrBadFile, fmt.Sprintf("node %d has a count of %d but its children add up to %d", k, v.N, sum))
+        }
+    }
+    for k := 1; k < len(nodes); k++ {
+        if !linked[k] { return fail(ErrBadFile, fmt.Sprintf("node %d isn't linked from any parent", k)) }
+    }
+    return nil
+} //end func validateNodes
+func validatePoints(nodes []node) error {
+    //Checks that the point coordinates of every leaf node holding them lead down from the root to that very leaf, as
+    //the queries & insertions assume. The nodes must have been validated. The error names the first offending node.
+    for k := range nodes {
+        if !nodes[k].LEAF { continue }
+        for i := range nodes[k].PTS {
+            nodeIdx := 0
+            for nodeIdx >= 0 && !nodes[nodeIdx].LEAF { //walk down to the point's leaf, if any
+                nodeIdx = nodes[nodeIdx].CHILDREN[assignOctant(&(nodes[nodeIdx].CENTER),&(nodes[k].PTS[i]))]
+            }
+            if nodeIdx != k {
+                return fail(ErrBadFile, fmt.Sprintf("node %d holds the point '%s' lying outside its octant",
+                                                    k, nodes[k].KEYS[i]))
+            }
+        }
+    }
+    return nil
+} //end func validatePoints
+func cubeBounds(pts []DataCoords, pad bool) cellBounds {
+    //Computes the cube centered on the bounding box of the data points whose side is the box's largest extent, possibly
+    //padded to the next power of two.
+    var(
+        box    = dataBounds(pts)
+        cube   cellBounds
+        center DataCoords
+        side   = 0.
+    )
+    for k := range center {
+        center[k] = 0.5*(box.MIN[k] + box.MAX[k])
+        side      = math.Max(box.MAX[k] - box.MIN[k], side)
+    }
+    if pad && side > 0. { side = math.Exp2(math.Ceil(math.Log2(side))) }
+    for k := range center { //mind the rounding errors so that the cube encloses the box
+        cube.MIN[k] = math.Min(center[k] - 0.5*side, box.MIN[k])
+        cube.MAX[k] = math.Max(center[k] + 0.5*side, box.MAX[k])
+    }
+    return cube
+} //end func cubeBounds
+func dataBounds(pts []DataCoords) cellBounds {
+    //Computes the bounding box of the data points.
+    bounds := cellBounds{ MIN: DataCoords{ math.Inf(1), math.Inf(1), math.Inf(1) },
+                          MAX: DataCoords{ math.Inf(-1), math.Inf(-1), math.Inf(-1) } }
+    for _, v := range pts {
+        for k := range v {
+            bounds.MIN[k] = math.Min(v[k], bounds.MIN[k])
+            bounds.MAX[k] = math.Max(v[k], bounds.MAX[k])
+        }
+    }
+    return bounds
+} //end func dataBounds
+func extent(pts []DataCoords) (side float64) {
+    //Computes the largest extent of the data points along the axes.
+    bounds := dataBounds(pts)
+    for k := range bounds.MIN { side = math.Max(bounds.MAX[k] - bounds.MIN[k], side) }
+    return
+} //end func extent
+func (t *Octree) indexKeys() {
+    //Builds the index of the leaf nodes keyed on the data-point identifiers, and the parent links, if not already done.
+    if t.index != nil { return }
+    t.index = make(map[string]int, t.nodes[0].N)
+    for k, v := range t.nodes {
+        if !v.LEAF { continue }
+        for _, key := range v.KEYS { t.index[key] = k }
+    }
+    t.linkParents()
+} //end func (*Octree).indexKeys
+func lessOrdinate(a, b float64) bool {
+    //Orders two ordinates as sort.Float64Slice does, NaN values coming first.
+    return a < b || (math.IsNaN(a) && !math.IsNaN(b))
+} //end func lessOrdinate
+func (t *Octree) linkParents() {
+    //Links every node to its parent, the root to none.
+    t.parents = make([]int, len(t.nodes))
+    t.parents[0] = -1
+    for k, v := range t.nodes {
+        if v.LEAF { continue }
+        for _, childIdx := range v.CHILDREN {
+            if childIdx >= 0 { t.parents[childIdx] = k }
+        }
+    }
+} //end func (*Octree).linkParents
+func makeBuilder(ctx context.Context, cfg Config, numPts int, refDiag *medianDiag) (*builder, error) {
+    //Creates an octree builder for a context, a resolved configuration and a number of data points, the latter being 0
+    //to forgo progress reporting, which adds the geometric-median diagnostics to the given ones.
+    calcCenter, err := makeCalcCenter(cfg, refDiag)
+    if err != nil { return nil, err }
+    report := cfg.PROGRESS
+    if numPts == 0 { report = nil }
+    return &builder{ calcCenter:  calcCenter,
+                     ctx:         ctx,
+                     maxDepth:    cfg.MAXDEPTH,
+                     minCellSize: cfg.MINCELLSIZE,
+                     regular:     cfg.METHOD == "Regular",
+                     report:      report,
+                     sparse:      cfg.SPARSE,
+                     start:       time.Now(),
+                     terminal_N:  cfg.TERMINAL_N,
+                     total:       numPts,
+                     tokens:      make(chan struct{}, cfg.WORKERS - 1) }, nil
 } //end func makeBuilder
-func makeCalcCenter(method string) centerFn {
+func makeCalcCenter(cfg Config, refDiag *medianDiag) (centerFn, error) {
+    //Creates the center calculator for the partitioning method of a configuration, the geometric-median method adding
+    //its convergence diagnostics to the given ones, if any.
+    method := cfg.METHOD
     switch method {
         case "Centroid":
-            return func(refPoints *DataSet) DataCoords {
+            return func(pts []DataCoords, _ *cellBounds) (DataCoords, error) {
                     var(
                         centroid DataCoords
-                        numPts   = float64(len(*refPoints))
+                        numPts   = float64(len(pts))
                     )
-                    for _, v := range *refPoints {
+                    for _, v := range pts {
                         for k := range centroid { centroid[k] += v[k] }
                     }
                     for k := range centroid { centroid[k] /= numPts }
-                    return centroid
-                   }
+                    return centroid, nil
+                   }, nil
         case "DataMidPoint":
-            return func(refPoints *DataSet) DataCoords {
+            return func(pts []DataCoords, _ *cellBounds) (DataCoords, error) {
                     type minMax struct {
                         MIN, MAX float64
                     }
@@ -480,7 +2082,7 @@ func makeCalcCenter(method string) centerFn {
                     for k := range dataBounds { //initialize
                         dataBounds[k] = minMax{ math.Inf(1), math.Inf(-1) }
                     }
-                    for _, v := range *refPoints { //compute min & max for each axis
+                    for _, v := range pts { //compute min & max for each axis
                         for k := range dataBounds {
                             dataBounds[k].MIN = math.Min(v[k], dataBounds[k].MIN)
                             dataBounds[k].MAX = math.Max(v[k], dataBounds[k].MAX)
@@ -488,12 +2090,13 @@ func makeCalcCenter(method string) centerFn {
                     }
                     return DataCoords{ 0.5*(dataBounds[0].MIN + dataBounds[0].MAX),
                                        0.5*(dataBounds[1].MIN + dataBounds[1].MAX),
-                                       0.5*(dataBounds[2].MIN + dataBounds[2].MAX) }
-                   }
+                                       0.5*(dataBounds[2].MIN + dataBounds[2].MAX) }, nil
+                   }, nil
         case "Geometric Median":
-            return func(refPoints *DataSet) DataCoords {
+            calcCentroid, _ := makeCalcCenter(Config{ METHOD: "Centroid" }, nil)    //initial guess &
+            calcMedians, _  := makeCalcCenter(Config{ METHOD: "XYZ Medians" }, nil) //fallback, shared by all the nodes
+            return func(pts []DataCoords, _ *cellBounds) (DataCoords, error) {
                     var(
-                        calcCentroid = makeCalcCenter("Centroid")
                         diffs        [4][3]float64 //estimate differences (diff[0] not used)
                         iterations   int           //iteration counter
                         medians      [4]DataCoords //geometric median estimates:
@@ -501,116 +2104,413 @@ func makeCalcCenter(method string) centerFn {
                                                    // [1] : Weiszfeld estimate
                                                    // [2] : Weiszfeld estimate
                                                    // [3] : Aitken estimate
+                        run          = MedianStats{ NODES: 1 } //diagnostics for this node
                     )
-                    medians[0] = calcCentroid(refPoints) //use centroid as init guess
-                    for iterations < MaxIterations {
+                    defer refDiag.merge(&run)
+                    medians[0], _ = calcCentroid(pts, nil) //use centroid as init guess
+                    for iterations < cfg.MAXITERATIONS {
                         for ctrl := 1; ctrl < 4; ctrl++ {
                             if ctrl != 3 { // calc a new estimate by Picard iteration
-                                medians[ctrl] = calcWeiszfeldEstimate(refPoints, &(medians[ctrl-1]))
+                                medians[ctrl] = calcWeiszfeldEstimate(pts, &(medians[ctrl-1]))
+                                run.ITERATIONS++
+                                run.WORST = run.ITERATIONS
                             } else {       // calc a new estimate by Aitken extrapolation
                                 medians[ctrl] = calcAitkenEstimate(medians[:3])
                             }
+                            if !isFinite(&(medians[ctrl])) { // fall back on the ordinate medians
+                                run.FALLBACKS++
+                                return calcMedians(pts, nil)
+                            }
                             for k := range diffs[0] { // calc discrepencies between estimates
                                 diffs[ctrl][k] = math.Abs(medians[ctrl][k] - medians[ctrl-1][k])
                             }
                             // check for convergence
-                            if math.Max(diffs[ctrl][0],math.Max(diffs[ctrl][1],diffs[ctrl][2])) < Tol {
-                                return medians[ctrl]
-                            }
+                            run.RESIDUAL = math.Max(diffs[ctrl][0],math.Max(diffs[ctrl][1],diffs[ctrl][2]))
+                            if run.RESIDUAL < cfg.TOL { return medians[ctrl], nil }
                         }
                         iterations += 3; // update iteration counter
                         for k := range diffs[0] {
                             if (diffs[1][k] > diffs[2][k]) && (diffs[2][k] > diffs[3][k]) { // check for monotonic trend
                                 medians[0][k] = medians[3][k] // update with Aitken value
+                                run.ACCEPTED[k]++
                             } else {
                                 medians[0][k] = medians[2][k] // else with Weiszfeld value
+                                run.REJECTED[k]++
                             }
                         }
                     }
-                    halt(fmt.Sprintf("maximum number of iterations (%d) exceeded", MaxIterations))
-                    panic("not reached")
-                   }
+                    return medians[0], fail(ErrNotConverged, fmt.Sprintf("maximum number of iterations (%d) exceeded",
+                                                                          cfg.MAXITERATIONS))
+                   }, nil
+        case "Regular":
+            return func(_ []DataCoords, refBounds *cellBounds) (DataCoords, error) {
+                    return DataCoords{ 0.5*(refBounds.MIN[0] + refBounds.MAX[0]),
+                                       0.5*(refBounds.MIN[1] + refBounds.MAX[1]),
+                                       0.5*(refBounds.MIN[2] + refBounds.MAX[2]) }, nil
+                   }, nil
         case "XYZ Medians":
-            return func(refPoints *DataSet) DataCoords {
+            var buffers sync.Pool //scratch buffers for the ordinates, reused across nodes & goroutines
+            return func(pts []DataCoords, _ *cellBounds) (DataCoords, error) {
                     var(
                         medians   DataCoords
-                        numPts    = len(*refPoints)
+                        numPts    = len(pts)
                         midIdx    = int(math.Trunc(float64(numPts)/2.))
-                        ordinates = make([]float64, numPts)
+                        ordinates []float64
                     )
+                    if buf, ok := buffers.Get().(*[]float64); ok && cap(*buf) >= numPts { ordinates = (*buf)[:numPts]
+                    } else                                                             { ordinates = make([]float64, numPts) }
+                    defer buffers.Put(&ordinates)
                     for k := range medians {
-                        i := 0
-                        for _, v := range *refPoints {
-                            ordinates[i] = v[k]
-                            i++
+                        for i, v := range pts { ordinates[i] = v[k] }
+                        medians[k] = selectOrdinate(ordinates, midIdx)
+                        if numPts % 2 == 0 { //average with the largest ordinate below the upper median
+                            lower := ordinates[0]
+                            for _, v := range ordinates[1:midIdx] {
+                                if lessOrdinate(lower, v) { lower = v }
+                            }
+                            medians[k] = (lower + medians[k]) / 2.
                         }
-                        sort.Sort(sort.Float64Slice(ordinates))
-                        if numPts % 2 != 0 { medians[k] = ordinates[midIdx]
-                        } else             { medians[k] = (ordinates[midIdx-1] + ordinates[midIdx]) / 2. }
                     }
-                    return medians
-                   }
+                    return medians, nil
+                   }, nil
         default:
-            halt("unrecognized method name '" + method + "'")
+            _methodsMu.RLock()
+            calcCenter, found := _methods[method]
+            _methodsMu.RUnlock()
+            if !found { return nil, fail(ErrBadMethod, "unrecognized method name '" + method + "'") }
+            return func(pts []DataCoords, _ *cellBounds) (DataCoords, error) {
+                    center := calcCenter(pts)
+                    for _, v := range center {
+                        if math.IsNaN(v) {
+                            return center, fail(ErrBadMethod, "the method '" + method + "' gave an invalid partition point")
+                        }
+                    }
+                    return center, nil
+                   }, nil
     }
-    panic("not reached")
 } //end fun makeCalcCenter
+func partition(refCenter *DataCoords, keys []string, pts []DataCoords) (ranges [9]int) {
+    //Rearranges the data points in place, American-flag-sort style, so that those of each octant are contiguous.
+    //Returns the bounds of the octant ranges: octant k holds the points in [ranges[k],ranges[k+1]).
+    for i := range pts { ranges[assignOctant(refCenter,&pts[i]) + 1]++ } //count the points per octant
+    for k := 1; k < len(ranges); k++ { ranges[k] += ranges[k-1] }
+    next := ranges                                                      //next unsorted slot per octant
+    for k := 0; k < 8; k++ {
+        for next[k] < ranges[k+1] {
+            i, octant := next[k], assignOctant(refCenter,&pts[next[k]])
+            if octant == k { next[k]++; continue }
+            j := next[octant] // swap the point into its octant's next slot
+            keys[i], keys[j] = keys[j], keys[i]
+            pts[i], pts[j]   = pts[j], pts[i]
+            next[octant]++
+        }
+    }
+    return
+} //end func partition
+func selectOrdinate(ordinates []float64, k int) float64 {
+    //Rearranges the ordinates in place so that the k-th smallest, counting from 0, is at index k with none larger before
+    //it and none smaller after it, as would sorting them with sort.Float64Slice. Returns the k-th smallest ordinate.
+    //Quickselect with median-of-three pivots and three-way partitions, the remaining range being sorted should the
+    //partitions keep being lopsided, in O(n) time on average and O(n log n) at worst.
+    lo, hi := 0, len(ordinates) //range holding the k-th smallest ordinate
+    for budget := 2*bits.Len(uint(hi)); hi - lo > 1; budget-- {
+        if budget == 0 {
+            sort.Sort(sort.Float64Slice(ordinates[lo:hi]))
+            break
+        }
+        a, pivot, c := ordinates[lo], ordinates[lo + (hi - lo)/2], ordinates[hi-1] //median of three
+        if lessOrdinate(pivot, a) { a, pivot = pivot, a }
+        if lessOrdinate(c, pivot) {
+            pivot = c
+            if lessOrdinate(pivot, a) { pivot = a }
+        }
+        lt, i, gt := lo, lo, hi //partition into [lo,lt) < pivot, [lt,gt) = pivot & [gt,hi) > pivot
+        for i < gt {
+            switch {
+                case lessOrdinate(ordinates[i], pivot):
+                    ordinates[lt], ordinates[i] = ordinates[i], ordinates[lt]
+                    lt++
+                    i++
+                case lessOrdinate(pivot, ordinates[i]):
+                    gt--
+                    ordinates[gt], ordinates[i] = ordinates[i], ordinates[gt]
+                default:
+                    i++
+            }
+        }
+        switch {
+            case k < lt:  hi = lt
+            case k >= gt: lo = gt
+            default:      return ordinates[k]
+        }
+    }
+    return ordinates[k]
+} //end func selectOrdinate
+func (t *Octree) spliceLeaf(leafIdx int, subtree []node) {
+    //Replaces a leaf node by a subtree built separately: its root takes the leaf's place and its other nodes are
+    //appended to the octree, their child links being offset accordingly. The key index & parent links are updated for
+    //the new nodes.
+    offset := len(t.nodes) - 1
+    for k := range subtree {
+        if !subtree[k].LEAF {
+            for i := range subtree[k].CHILDREN {
+                if subtree[k].CHILDREN[i] >= 0 { subtree[k].CHILDREN[i] += offset }
+            }
+        }
+    }
+    t.nodes[leafIdx] = subtree[0]
+    t.nodes          = append(t.nodes, subtree[1:]...)
+    t.parents        = append(t.parents, make([]int, len(subtree) - 1)...)
+    for k := range subtree {
+        nodeIdx := leafIdx
+        if k > 0 { nodeIdx = k + offset }
+        if !t.nodes[nodeIdx].LEAF {
+            for _, childIdx := range t.nodes[nodeIdx].CHILDREN {
+                if childIdx >= 0 { t.parents[childIdx] = nodeIdx }
+            }
+            continue
+        }
+        for _, key := range t.nodes[nodeIdx].KEYS { t.index[key] = nodeIdx }
+    }
+} //end func (*Octree).spliceLeaf
+////Geometric queries
+func (b cellBounds) child(refCenter *DataCoords, octant int) cellBounds {
+    //Computes the bounds of a child octant following the rule established by assignOctant.
+    for k := range b.MIN {
+        if octant & (1 << uint(k)) != 0 { b.MIN[k], b.OPEN[k] = (*refCenter)[k], true
+        } else                         { b.MAX[k]            = (*refCenter)[k] }
+    }
+    return b
+} //end func (cellBounds).child
+func (b cellBounds) distance2(refPoint *DataCoords) (dist2 float64) {
+    //Computes the squared Euclidean distance from a point to the nearest point of the bounds.
+    for k := range b.MIN {
+        diff := 0.
+        if        (*refPoint)[k] < b.MIN[k] { diff = b.MIN[k] - (*refPoint)[k]
+        } else if (*refPoint)[k] > b.MAX[k] { diff = (*refPoint)[k] - b.MAX[k] }
+        dist2 += diff * diff
+    }
+    return
+} //end func (cellBounds).distance2
+func (b cellBounds) farDistance2(refPoint *DataCoords) (dist2 float64) {
+    //Computes the squared Euclidean distance from a point to the farthest point of the bounds.
+    for k := range b.MIN {
+        diff := math.Max(math.Abs((*refPoint)[k] - b.MIN[k]), math.Abs(b.MAX[k] - (*refPoint)[k]))
+        dist2 += diff * diff
+    }
+    return
+} //end func (cellBounds).farDistance2
+func (b cellBounds) inside(refMin, refMax *DataCoords) bool {
+    //Checks whether the bounds lie entirely inside a closed box.
+    for k := range b.MIN {
+        if b.MIN[k] < (*refMin)[k] || b.MAX[k] > (*refMax)[k] { return false }
+    }
+    return true
+} //end func (cellBounds).inside
+func (b cellBounds) outside(refMin, refMax *DataCoords) bool {
+    //Checks whether the bounds lie entirely outside a closed box, minding the exclusive lower bounds.
+    for k := range b.MIN {
+        if b.MAX[k] < (*refMin)[k] || b.MIN[k] > (*refMax)[k] || (b.OPEN[k] && b.MIN[k] == (*refMax)[k]) { return true }
+    }
+    return false
+} //end func (cellBounds).outside
+func (b cellBounds) splittable(refCenter *DataCoords) bool {
+    //Checks whether a partition point strictly inside the bounds halves them along at least one axis.
+    for k := range b.MIN {
+        if (*refCenter)[k] > b.MIN[k] && (*refCenter)[k] < b.MAX[k] { return true }
+    }
+    return false
+} //end func (cellBounds).splittable
+func (t *Octree) collectKeys(nodeIdx int, keys []string) []string {
+    //Appends the identifiers of all the data points associated with a node.
+    thisNode := &t.nodes[nodeIdx]
+    switch {
+        case thisNode.N == 0:
+        case !thisNode.LEAF:
+            for _, childIdx := range thisNode.CHILDREN {
+                if childIdx >= 0 { keys = t.collectKeys(childIdx, keys) }
+            }
+        default:
+            keys = append(keys, thisNode.KEYS...)
+    }
+    return keys
+} //end func (*Octree).collectKeys
+func distance2(refPointA, refPointB *DataCoords) (dist2 float64) {
+    //Computes the squared Euclidean distance between two points.
+    for k := range refPointA {
+        diff := (*refPointA)[k] - (*refPointB)[k]; dist2 += diff * diff
+    }
+    return
+} //end func distance2
+func rootBounds() cellBounds {
+    //Returns the unbounded extent of the root node.
+    return cellBounds{ MIN: DataCoords{ math.Inf(-1), math.Inf(-1), math.Inf(-1) },
+                       MAX: DataCoords{ math.Inf(1), math.Inf(1), math.Inf(1) } }
+} //end func rootBounds
+func (q searchQueue) Len() int            { return len(q) }
+func (q searchQueue) Less(i, j int) bool  { return q[i].DIST2 < q[j].DIST2 }
+func (q searchQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *searchQueue) Push(x interface{}) { *q = append(*q, x.(searchItem)) }
+func (q *searchQueue) Pop() interface{} {
+    //Removes the last item as required by container/heap.
+    old  := *q
+    item := old[len(old)-1]
+    *q    = old[:len(old)-1]
+    return item
+} //end func (*searchQueue).Pop
 ////Reporting
-func calcStats() {
+func (t *Octree) calcStats() {
     //Compile basic octree statistics
+    stats                           := &t.stats
     minPts, maxPts                  := mathutil.MaxInt, mathutil.MinInt
     numParents, numLeaves, numEmpty := 0, 0, 0
-    _stats.LEAFCOUNTS                = nil
-    for _, v := range _octree {
-        if v.N > _stats.STOP {
+    numOversized, numAbsent         := 0, 0
+    stats.LEAFCOUNTS                 = nil
+    for _, v := range t.nodes {
+        if !v.LEAF {
             numParents++
+            for _, childIdx := range v.CHILDREN {
+                if childIdx < 0 { numAbsent++ }
+            }
         } else {
             numLeaves++
-            _stats.LEAFCOUNTS = append(_stats.LEAFCOUNTS, v.N)
-            minPts, maxPts    = mathutil.Min(minPts, v.N), mathutil.Max(maxPts, v.N)
-            if v.N == 0 { numEmpty++ }
+            stats.LEAFCOUNTS = append(stats.LEAFCOUNTS, v.N)
+            minPts, maxPts   = mathutil.Min(minPts, v.N), mathutil.Max(maxPts, v.N)
+            if v.N == 0          { numEmpty++ }
+            if v.N > stats.STOP  { numOversized++ }
         }
     }
     //Compute the population mean (mu) and standard deviation (sigma) of the leaf counts
     mu, sigma := 0., 0.
-    for _, v := range _stats.LEAFCOUNTS { mu += float64(v) }
+    for _, v := range stats.LEAFCOUNTS { mu += float64(v) }
     mu /= float64(numLeaves)
-    for _, v := range _stats.LEAFCOUNTS { diff := float64(v) - mu; sigma += diff * diff }
+    for _, v := range stats.LEAFCOUNTS { diff := float64(v) - mu; sigma += diff * diff }
     sigma = math.Sqrt(sigma / float64(numLeaves))
     //Prettify/stringify the results
-    _stats.NUMPTS     = humanize.Comma(int64(_octree[0].N))
-    _stats.MINPTS     = humanize.Comma(int64(minPts))
-    _stats.MAXPTS     = humanize.Comma(int64(maxPts))
-    _stats.NUMPARENTS = humanize.Comma(int64(numParents))
-    _stats.NUMLEAVES  = humanize.Comma(int64(numLeaves))
-    _stats.NUMEMPTY   = humanize.Comma(int64(numEmpty))
-    _stats.OCTREELEN  = humanize.Comma(int64(_stats.SIZE))
-    _stats.TERMINAL_N = humanize.Comma(int64(_stats.STOP))
-    _stats.MU         = fmt.Sprintf("%.2f", mu)
-    _stats.SIGMA      = fmt.Sprintf("%.2f", sigma)
-    _stats.LOWER      = fmt.Sprintf("%.2f", mu - sigma)
-    _stats.UPPER      = fmt.Sprintf("%.2f", mu + sigma)
-    _stats.XMIN       = minPts
-    _stats.XMAX       = maxPts
-} //end func calcStats
-func halt(msg string) {
+    stats.NUMPTS     = humanize.Comma(int64(t.nodes[0].N))
+    stats.MINPTS     = humanize.Comma(int64(minPts))
+    stats.MAXPTS     = humanize.Comma(int64(maxPts))
+    stats.NUMPARENTS = humanize.Comma(int64(numParents))
+    stats.NUMLEAVES  = humanize.Comma(int64(numLeaves))
+    stats.NUMEMPTY   = humanize.Comma(int64(numEmpty))
+    stats.NUMOVER    = humanize.Comma(int64(numOversized))
+    stats.NUMABSENT  = humanize.Comma(int64(numAbsent))
+    stats.OCTREELEN  = humanize.Comma(int64(stats.SIZE))
+    stats.TERMINAL_N = humanize.Comma(int64(stats.STOP))
+    stats.MU         = fmt.Sprintf("%.2f", mu)
+    stats.SIGMA      = fmt.Sprintf("%.2f", sigma)
+    stats.LOWER      = fmt.Sprintf("%.2f", mu - sigma)
+    stats.UPPER      = fmt.Sprintf("%.2f", mu + sigma)
+    stats.XMIN       = minPts
+    stats.XMAX       = maxPts
+    t.median.mu.Lock()
+    stats.MEDIAN     = t.median.STATS
+    t.median.mu.Unlock()
+    t.stale          = false
+} //end func (*Octree).calcStats
+func (d *medianDiag) merge(refRun *MedianStats) {
+    //Adds the geometric-median diagnostics of a node to the running ones, if any.
+    if d == nil { return }
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    d.STATS.NODES      += refRun.NODES
+    d.STATS.ITERATIONS += refRun.ITERATIONS
+    d.STATS.WORST       = mathutil.Max(refRun.WORST, d.STATS.WORST)
+    d.STATS.RESIDUAL    = math.Max(refRun.RESIDUAL, d.STATS.RESIDUAL)
+    d.STATS.FALLBACKS  += refRun.FALLBACKS
+    for k := range d.STATS.ACCEPTED {
+        d.STATS.ACCEPTED[k] += refRun.ACCEPTED[k]
+        d.STATS.REJECTED[k] += refRun.REJECTED[k]
+    }
+} //end func (*medianDiag).merge
+////Default octree
+func getDefault() *Octree {
+    //Returns the octree used by the package-level functions.
+    _defaultMu.RLock()
+    defer _defaultMu.RUnlock()
+    return _default
+} //end func getDefault
+func (t *Octree) isEmpty() bool {
+    //Checks for a missing or empty octree.
+    return t == nil || t.stats.SIZE == 0
+} //end func (*Octree).isEmpty
+func setDefault(t *Octree) {
+    //Sets the octree used by the package-level functions.
+    _defaultMu.Lock()
+    _default = t
+    _defaultMu.Unlock()
+} //end func setDefault
+////Utilities
+func (r *countReader) Read(p []byte) (int, error) {
+    //Reads from the underlying reader, adding the number of bytes read to the count.
+    n, err := r.reader.Read(p)
+    r.n    += int64(n)
+    return n, err
+} //end func (*countReader).Read
+func (r *countReader) ReadByte() (byte, error) {
+    //Reads a single byte from the underlying reader, adding it to the count.
+    var b [1]byte
+    _, err := io.ReadFull(r, b[:])
+    return b[0], err
+} //end func (*countReader).ReadByte
+func (w *countWriter) Write(p []byte) (int, error) {
+    //Writes to the underlying writer, adding the number of bytes written to the count.
+    n, err := w.writer.Write(p)
+    w.n    += int64(n)
+    return n, err
+} //end func (*countWriter).Write
+func fail(err error, msg string) error {
+    //Creates an *Error on behalf of the calling function from an underlying error and a description.
+    op           := "octree"
     pc, _, _, ok := runtime.Caller(1)
     details      := runtime.FuncForPC(pc)
-    if ok && details != nil {
-        log.Fatalln(fmt.Sprintf("\a%s: %s", details.Name(), msg))
+    if ok && details != nil { op = details.Name() }
+    return &Error{ Op: op, Msg: msg, Err: err }
+} //end func fail
+func (t *Octree) hasCoords() bool {
+    //Checks that every leaf node holds the coordinates of its points.
+    for k := range t.nodes {
+        if t.nodes[k].LEAF && len(t.nodes[k].PTS) != t.nodes[k].N { return false }
+    }
+    return true
+} //end func (*Octree).hasCoords
+func isFinite(refPoint *DataCoords) bool {
+    //Checks that none of a point's coordinates is NaN or infinite.
+    for _, v := range *refPoint {
+        if math.IsNaN(v) || math.IsInf(v, 0) { return false }
+    }
+    return true
+} //end func isFinite
+func (b *builder) progress(numNodes, numPts int) {
+    //Reports the nodes created and the points placed in leaf nodes to the progress callback, if any.
+    if b.report == nil { return }
+    b.mu.Lock()
+    b.created += numNodes
+    b.placed  += numPts
+    b.report(Progress{ POINTS: b.placed, TOTAL: b.total, NODES: b.created, ELAPSED: time.Since(b.start) })
+    b.mu.Unlock()
+} //end func (*builder).progress
+func (b *builder) splice(nodes, subtree []node) []node {
+    //Appends a separately built subtree to a slice of nodes, offsetting its child links accordingly.
+    offset := len(nodes)
+    for k := range subtree {
+        if !subtree[k].LEAF {
+            for i := range subtree[k].CHILDREN {
+                if subtree[k].CHILDREN[i] >= 0 { subtree[k].CHILDREN[i] += offset }
+            }
+        }
     }
-    log.Fatalln("\aoctree: FATAL ERROR!")
-} //end func halt
-func updateProgressBar(title string, current, total int) {
+    return append(nodes, subtree...)
+} //end func (*builder).splice
+func updateProgressBar(writer io.Writer, title string, current, total int) {
     //code derived from Graham King's post "Pretty command line / console output on Unix in Python and Go Lang"
     //(http://www.darkcoding.net/software/pretty-command-line-console-output-on-unix-in-python-and-go-lang/)
     prefix := fmt.Sprintf("%s: %d / %d ", title, current, total)
     amount := int(0.1 + float32(_progressBarLen) * float32(current) / float32(total))
     remain := _progressBarLen - amount
     bar    := strings.Repeat("\u2588", amount) + strings.Repeat("\u2591", remain)
-    os.Stdout.WriteString(prefix + bar + "\r")
-    if current == total { os.Stdout.WriteString(strings.Repeat(" ", len(prefix) + _progressBarLen) + "\r") }
-    os.Stdout.Sync()
+    io.WriteString(writer, prefix + bar + "\r")
+    if current == total { io.WriteString(writer, strings.Repeat(" ", len(prefix) + _progressBarLen) + "\r") }
     return
 } //end func updateProgressBar
 //===== Copyright (c) 2016 Yves Beaudoin - All rights reserved - MIT LICENSE (MIT) - Email: webpraxis@gmail.com ================
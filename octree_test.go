@@ -0,0 +1,594 @@
+package octree
+
+import(
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "math/rand"
+    "os"
+    "path/filepath"
+    "reflect"
+    "runtime"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+)
+
+var testMethods = []string{ "Centroid", "DataMidPoint", "Geometric Median", "Regular", "XYZ Medians" }
+
+func testConfigs() []Config {
+    //Returns a build configuration per partitioning method, with small leaves, with and without the sparse layout.
+    var configs []Config
+    for _, method := range testMethods {
+        for _, sparse := range []bool{ false, true } {
+            configs = append(configs, Config{ METHOD: method, TERMINAL_N: 8, MAXITERATIONS: 100000, SPARSE: sparse })
+        }
+    }
+    return configs
+} //end func testConfigs
+func testLabel(cfg Config) string {
+    //Names a build configuration in the test messages.
+    if cfg.SPARSE { return cfg.METHOD + " (sparse)" }
+    return cfg.METHOD
+} //end func testLabel
+func testPoints(numPts int, seed int64) DataSet {
+    //Returns a data set half of whose ordinates lie on a 1/16 grid, so that duplicates and points on the partition
+    //planes of the regular & median methods are common.
+    var(
+        gen    = rand.New(rand.NewSource(seed))
+        points = make(DataSet, numPts)
+    )
+    for i := 0; i < numPts; i++ { points[fmt.Sprintf("#%d", i)] = testPoint(gen) }
+    return points
+} //end func testPoints
+func testPoint(gen *rand.Rand) (pt DataCoords) {
+    //Returns a random point, each ordinate lying on a 1/16 grid half of the time.
+    for k := range pt {
+        if gen.Intn(2) == 0 { pt[k] = float64(gen.Intn(17)) / 16.
+        } else              { pt[k] = gen.Float64() }
+    }
+    return pt
+} //end func testPoint
+func sortedKeys(keys []string) []string {
+    //Returns a sorted copy of the keys, nil for none.
+    if len(keys) == 0 { return nil }
+    keys = append([]string(nil), keys...)
+    sort.Strings(keys)
+    return keys
+} //end func sortedKeys
+func testTree(t *testing.T, cfg Config, points DataSet) *Octree {
+    //Builds an octree, failing the test on error.
+    t.Helper()
+    tree, err := New(cfg, &points)
+    if err != nil { t.Fatalf("%s: %v", testLabel(cfg), err) }
+    return tree
+} //end func testTree
+
+func TestParallelLayout(t *testing.T) {
+    //Concurrent builds must give the very node layout of a serial build.
+    points := testPoints(6*_parallelGrain, 1)
+    for _, cfg := range testConfigs() {
+        cfg.WORKERS = 1
+        serial     := testTree(t, cfg, points)
+        cfg.WORKERS = 8
+        parallel   := testTree(t, cfg, points)
+        if !reflect.DeepEqual(serial.nodes, parallel.nodes) {
+            t.Errorf("%s: the parallel layout differs from the serial one", testLabel(cfg))
+        }
+    }
+} //end func TestParallelLayout
+
+func TestQueries(t *testing.T) {
+    //The queries must agree with a brute-force search, including for points on partition planes: half the query points
+    //lie on the 1/16 grid and the other half are partition points.
+    queries := []struct{
+        name  string
+        check func(tree *Octree, points DataSet, pt DataCoords, size float64) error
+    }{
+        { "KNearest",     checkKNearest },
+        { "WithinRadius", checkWithinRadius },
+        { "InBox",        checkInBox },
+    }
+    points := testPoints(3000, 2)
+    for _, cfg := range testConfigs() {
+        var(
+            gen  = rand.New(rand.NewSource(3))
+            tree = testTree(t, cfg, points)
+        )
+        for q := 0; q < 50; q++ {
+            var(
+                pt   DataCoords
+                size = float64(1 + gen.Intn(4)) / 16. //radius or box half-side
+            )
+            for k := range pt { pt[k] = float64(gen.Intn(17)) / 16. }
+            if q % 2 == 1 { pt = tree.nodes[gen.Intn(len(tree.nodes))].CENTER } //a partition point, if any
+            for _, query := range queries {
+                if err := query.check(tree, points, pt, size); err != nil {
+                    t.Fatalf("%s: %s: %v", testLabel(cfg), query.name, err)
+                }
+            }
+        }
+    }
+} //end func TestQueries
+func checkKNearest(tree *Octree, points DataSet, pt DataCoords, _ float64) error {
+    //Checks the 10 nearest neighbours of a point against the sorted distances of all the points.
+    var dists []float64
+    for _, v := range points {
+        v := v
+        dists = append(dists, math.Sqrt(distance2(&pt, &v)))
+    }
+    sort.Float64s(dists)
+    neighbors, err := tree.KNearest(pt, 10)
+    if err != nil { return err }
+    if len(neighbors) != 10 { return fmt.Errorf("%v gave %d neighbours", pt, len(neighbors)) }
+    for i, v := range neighbors {
+        if v.DISTANCE != dists[i] {
+            return fmt.Errorf("%v: neighbour %d at %g, brute force %g", pt, i, v.DISTANCE, dists[i])
+        }
+    }
+    return nil
+} //end func checkKNearest
+func checkWithinRadius(tree *Octree, points DataSet, center DataCoords, r float64) error {
+    //Checks the points within a distance of a point against all the points, those at the distance included.
+    var want []string
+    for key, v := range points {
+        v := v
+        if distance2(&center, &v) <= r*r { want = append(want, key) }
+    }
+    keys, err := tree.WithinRadius(center, r)
+    if err != nil { return err }
+    if !reflect.DeepEqual(sortedKeys(keys), sortedKeys(want)) {
+        return fmt.Errorf("%v, %g gave %d keys, brute force %d", center, r, len(keys), len(want))
+    }
+    return nil
+} //end func checkWithinRadius
+func checkInBox(tree *Octree, points DataSet, center DataCoords, half float64) error {
+    //Checks the points inside a cube centered on a point against all the points, those on its faces included.
+    var(
+        max  = DataCoords{ center[0] + half, center[1] + half, center[2] + half }
+        min  = DataCoords{ center[0] - half, center[1] - half, center[2] - half }
+        want []string
+    )
+    points: for key, v := range points {
+        for k := range v {
+            if v[k] < min[k] || v[k] > max[k] { continue points }
+        }
+        want = append(want, key)
+    }
+    keys, err := tree.InBox(min, max)
+    if err != nil { return err }
+    if !reflect.DeepEqual(sortedKeys(keys), sortedKeys(want)) {
+        return fmt.Errorf("%v to %v gave %d keys, brute force %d", min, max, len(keys), len(want))
+    }
+    return nil
+} //end func checkInBox
+
+func TestEdits(t *testing.T) {
+    //Random edits must keep the octree valid and in agreement with the data set: the leaf splits, subtree collapses,
+    //sparse leave-outs and the key index are checked against a brute-force search as the edits proceed. The remaining
+    //points are then deleted from a copy imported without their coordinates.
+    edits := []struct{
+        name string
+        edit func(tree *Octree, points DataSet, gen *rand.Rand) error
+    }{
+        { "Insert", insertPoint },
+        { "Delete", deletePoint },
+    }
+    for _, cfg := range testConfigs() {
+        var(
+            gen    = rand.New(rand.NewSource(7))
+            points = testPoints(500, 8)
+            tree   = testTree(t, cfg, points)
+        )
+        for step := 1; step <= 2000; step++ {
+            edit := edits[gen.Intn(len(edits))]
+            if err := edit.edit(tree, points, gen); err != nil {
+                t.Fatalf("%s: step %d: %s: %v", testLabel(cfg), step, edit.name, err)
+            }
+            if step % 100 != 0 { continue }
+            if err := checkTree(tree, points, gen); err != nil { t.Fatalf("%s: step %d: %v", testLabel(cfg), step, err) }
+        }
+
+        var(
+            data     bytes.Buffer
+            imported Octree
+        )
+        if _, err := tree.WriteTo(&data); err != nil { t.Fatal(err) }
+        if _, err := imported.ReadFrom(&data); err != nil { t.Fatal(err) }
+        for key := range points {
+            if err := imported.Delete(key); err != nil { t.Fatalf("%s: imported: Delete: %v", testLabel(cfg), err) }
+        }
+        if err := validateNodes(imported.nodes, cfg.SPARSE); err != nil || imported.nodes[0].N != 0 {
+            t.Fatalf("%s: imported: %d points left: %v", testLabel(cfg), imported.nodes[0].N, err)
+        }
+    }
+} //end func TestEdits
+func checkTree(tree *Octree, points DataSet, gen *rand.Rand) error {
+    //Checks the structure of an edited octree, its key index and its parent nodes' counts, every point being found by
+    //Query and the nearest neighbours of a random point by KNearest.
+    if err := validateNodes(tree.nodes, tree.config.SPARSE); err != nil { return err }
+    if err := validatePoints(tree.nodes); err != nil { return err }
+    if tree.nodes[0].N != len(points) { return fmt.Errorf("%d points for %d in the data set", tree.nodes[0].N, len(points)) }
+    for k, v := range tree.nodes {
+        switch {
+            case !v.LEAF && v.N <= tree.stats.STOP:
+                return fmt.Errorf("node %d is a parent with %d points", k, v.N)
+            case v.LEAF && v.N == 0 && k > 0 && tree.config.SPARSE:
+                return fmt.Errorf("node %d is an empty leaf in a sparse octree", k)
+        }
+    }
+    if len(tree.index) != len(points) { return fmt.Errorf("%d keys indexed for %d points", len(tree.index), len(points)) }
+    for key, pt := range points {
+        pt := pt
+        leafIdx, found := tree.index[key]
+        if !found || !contains(tree.nodes[leafIdx].KEYS, key) { return fmt.Errorf("'%s' is indexed to node %d", key, leafIdx) }
+        keys, err := tree.Query(&pt)
+        if err != nil { return err }
+        if !contains(keys, key) { return fmt.Errorf("Query(%v) misses '%s'", pt, key) }
+    }
+    return checkKNearest(tree, points, testPoint(gen), 0)
+} //end func checkTree
+func contains(keys []string, key string) bool {
+    //Reports whether a key is among others.
+    for _, v := range keys {
+        if v == key { return true }
+    }
+    return false
+} //end func contains
+func deletePoint(tree *Octree, points DataSet, _ *rand.Rand) error {
+    //Deletes a point, whose key must then be unknown.
+    for key := range points {
+        if err := tree.Delete(key); err != nil { return err }
+        delete(points, key)
+        if err := tree.Delete(key); !errors.Is(err, ErrKeyNotFound) { return fmt.Errorf("deleted '%s': %v", key, err) }
+        break
+    }
+    return nil
+} //end func deletePoint
+func insertPoint(tree *Octree, points DataSet, gen *rand.Rand) error {
+    //Inserts a random point under a new key, which must then be refused as a duplicate.
+    key, pt := fmt.Sprintf("+%d", gen.Int63()), testPoint(gen)
+    if err := tree.Insert(key, pt); err != nil { return err }
+    points[key] = pt
+    if err := tree.Insert(key, pt); !errors.Is(err, ErrDuplicateKey) { return fmt.Errorf("duplicate '%s': %v", key, err) }
+    return nil
+} //end func insertPoint
+
+func TestKeys(t *testing.T) {
+    //Keys which a CSV string would garble must survive an export & import, and the slice returned by Query must be the
+    //caller's to modify.
+    formats := []struct{
+        name  string
+        write func(tree *Octree, writer io.Writer) (int64, error)
+        read  func(tree *Octree, reader io.Reader) (int64, error)
+    }{
+        { "JSON",   (*Octree).WriteTo,                                                              (*Octree).ReadFrom },
+        { "binary", func(tree *Octree, writer io.Writer) (int64, error) { return tree.WriteBinary(writer, false) },
+                    (*Octree).ReadBinary },
+    }
+    var(
+        gen    = rand.New(rand.NewSource(9))
+        points = make(DataSet)
+    )
+    for _, key := range []string{ "a,b", ",", "", `"quoted"`, `back\slash`, "new\nline", "ünïcödé", " padded " } {
+        points[key] = testPoint(gen)
+    }
+    for i := 0; i < 100; i++ { points[fmt.Sprintf("%d,%d", i, i)] = testPoint(gen) }
+    tree := testTree(t, Config{ METHOD: "XYZ Medians", TERMINAL_N: 2 }, points)
+    for _, format := range formats {
+        var(
+            data     bytes.Buffer
+            imported Octree
+        )
+        if _, err := format.write(tree, &data); err != nil { t.Fatalf("%s: %v", format.name, err) }
+        if _, err := format.read(&imported, &data); err != nil { t.Fatalf("%s: %v", format.name, err) }
+        for key, pt := range points {
+            pt := pt
+            keys, err := imported.Query(&pt)
+            if err != nil || !contains(keys, key) { t.Fatalf("%s: Query(%v) misses %q: %v", format.name, pt, key, err) }
+            keys[0] = "x"
+            if keys, _ = imported.Query(&pt); !contains(keys, key) {
+                t.Fatalf("%s: the leaf of %q was changed through Query", format.name, key)
+            }
+        }
+        if err := imported.Delete("x"); !errors.Is(err, ErrKeyNotFound) { t.Fatalf("%s: deleted 'x': %v", format.name, err) }
+    }
+} //end func TestKeys
+
+func TestGuards(t *testing.T) {
+    //Nodes which can't or mustn't be split must become oversized leaves: duplicate points, and nodes at MAXDEPTH or no
+    //larger than MINCELLSIZE. The data points are distinct but for the duplicates.
+    guards := []struct{
+        name  string
+        tweak func(cfg *Config)
+        check func(tree *Octree, leafIdx, depth int) error
+    }{
+        { "duplicates", func(*Config) {}, func(tree *Octree, leafIdx, _ int) error {
+            duplicates := 0
+            for _, key := range tree.nodes[leafIdx].KEYS {
+                if key[0] == '=' { duplicates++ }
+            }
+            if duplicates > 0 && duplicates < 50 {
+                return fmt.Errorf("node %d holds %d of the 50 duplicates", leafIdx, duplicates)
+            }
+            return nil
+        } },
+        { "MAXDEPTH", func(cfg *Config) { cfg.MAXDEPTH = 2 }, func(tree *Octree, leafIdx, depth int) error {
+            v := tree.nodes[leafIdx]
+            if depth > 2 || (depth < 2 && v.N > tree.stats.STOP && extent(v.PTS) > 0) {
+                return fmt.Errorf("node %d at depth %d holds %d points", leafIdx, depth, v.N)
+            }
+            return nil
+        } },
+        { "MINCELLSIZE", func(cfg *Config) { cfg.MINCELLSIZE = 0.2 }, func(tree *Octree, leafIdx, _ int) error {
+            if v := tree.nodes[leafIdx]; v.N > tree.stats.STOP && extent(v.PTS) > 0.2 {
+                return fmt.Errorf("node %d spans %g with %d points", leafIdx, extent(v.PTS), v.N)
+            }
+            return nil
+        } },
+    }
+    var(
+        gen    = rand.New(rand.NewSource(10))
+        points = make(DataSet)
+    )
+    for i := 0; i < 2000; i++ { points[fmt.Sprintf("#%d", i)] = DataCoords{ gen.Float64(), gen.Float64(), gen.Float64() } }
+    for i := 0; i < 50; i++ { points[fmt.Sprintf("=%d", i)] = DataCoords{ 0.3, 0.3, 0.3 } }
+    for _, guard := range guards {
+        for _, cfg := range testConfigs() {
+            guard.tweak(&cfg)
+            var(
+                tree  = testTree(t, cfg, points)
+                visit func(nodeIdx, depth int) error
+            )
+            visit = func(nodeIdx, depth int) error {
+                if tree.nodes[nodeIdx].LEAF { return guard.check(tree, nodeIdx, depth) }
+                for _, childIdx := range tree.nodes[nodeIdx].CHILDREN {
+                    if childIdx < 0 { continue }
+                    if err := visit(childIdx, depth + 1); err != nil { return err }
+                }
+                return nil
+            }
+            if err := visit(0, 0); err != nil { t.Errorf("%s: %s: %v", guard.name, testLabel(cfg), err) }
+        }
+    }
+} //end func TestGuards
+
+func TestLegacyFile(t *testing.T) {
+    //Files written by v1 must be read, their CSV keys being split and a node with children being a leaf if its count
+    //doesn't exceed the termination criterion, as v1 exported parents with a center & children and leaves with keys.
+    empty := func(from, to int) (nodes string) { //v1 empty leaf nodes
+        for k := from; k <= to; k++ { nodes += fmt.Sprintf(`,{"id":%d,"N":0}`, k) }
+        return nodes
+    }
+    files := []struct{
+        name   string
+        data   string
+        want   map[DataCoords][]string //keys found by Query
+        parent []int                   //indices of the parent nodes
+    }{
+        { "flat",
+          `{"method":"Centroid","terminal_N":2,"time":0,"nodes":9,"octree":[` +
+          `{"id":0,"N":5,"center":[0.5,0.5,0.5],"children":[1,2,3,4,5,6,7,8]},{"id":1,"N":2,"keys":"a,b"}` + empty(2, 7) +
+          `,{"id":8,"N":3,"keys":"c,d,e"}]}`,
+          map[DataCoords][]string{ { 0.1, 0.1, 0.1 }: { "a", "b" }, { 0.9, 0.9, 0.9 }: { "c", "d", "e" },
+                                   { 0.9, 0.1, 0.1 }: nil },
+          []int{ 0 } },
+        { "nested",
+          `{"method":"XYZ Medians","terminal_N":2,"time":0,"nodes":17,"octree":[` +
+          `{"id":0,"N":5,"center":[0.5,0.5,0.5],"children":[1,2,3,4,5,6,7,8]},` +
+          `{"id":1,"N":3,"center":[0.25,0.25,0.25],"children":[9,10,11,12,13,14,15,16]}` + empty(2, 7) +
+          `,{"id":8,"N":2,"keys":"d,e"},{"id":9,"N":2,"keys":"a,b"},{"id":10,"N":1,"keys":"c"}` + empty(11, 16) + `]}`,
+          map[DataCoords][]string{ { 0.1, 0.1, 0.1 }: { "a", "b" }, { 0.3, 0.1, 0.1 }: { "c" },
+                                   { 0.9, 0.9, 0.9 }: { "d", "e" }, { 0.1, 0.3, 0.1 }: nil },
+          []int{ 0, 1 } },
+    }
+    for _, file := range files {
+        var tree Octree
+        if _, err := tree.ReadFrom(strings.NewReader(file.data)); err != nil { t.Fatalf("%s: %v", file.name, err) }
+        var parent []int
+        for k, v := range tree.nodes {
+            if !v.LEAF { parent = append(parent, k) }
+        }
+        if !reflect.DeepEqual(parent, file.parent) { t.Errorf("%s: parent nodes %v, want %v", file.name, parent, file.parent) }
+        for pt, want := range file.want {
+            pt := pt
+            if keys, err := tree.Query(&pt); err != nil || !reflect.DeepEqual(keys, want) {
+                t.Errorf("%s: Query(%v) gave %q, want %q: %v", file.name, pt, keys, want, err)
+            }
+        }
+        if cfg := tree.Config(); cfg.MAXITERATIONS != 1000 || cfg.TOL != 1e-12 {
+            t.Errorf("%s: the configuration %+v lacks the defaults", file.name, cfg)
+        }
+    }
+} //end func TestLegacyFile
+
+func TestCancel(t *testing.T) {
+    //A build must stop with an error wrapping ctx.Err() once its context is done, before or during the build, the
+    //default octree being left unchanged by MakeContext.
+    contexts := []struct{
+        name string
+        make func(cfg *Config) context.Context
+        want error
+    }{
+        { "cancelled", func(*Config) context.Context {
+            ctx, cancel := context.WithCancel(context.Background())
+            cancel()
+            return ctx
+        }, context.Canceled },
+        { "deadline", func(*Config) context.Context {
+            ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+            cancel()
+            return ctx
+        }, context.DeadlineExceeded },
+        { "during the build", func(cfg *Config) context.Context {
+            ctx, cancel := context.WithCancel(context.Background())
+            cfg.PROGRESS = func(p Progress) {
+                if p.NODES == 10 { cancel() }
+            }
+            return ctx
+        }, context.Canceled },
+    }
+    points := testPoints(3*_parallelGrain, 12)
+    for _, cfg := range testConfigs() {
+        initial, err := Make(cfg, &points)
+        if err != nil { t.Fatal(err) }
+        for _, c := range contexts {
+            cfg := cfg
+            tree, err := MakeContext(c.make(&cfg), cfg, &points)
+            if tree != nil || !errors.Is(err, c.want) {
+                t.Errorf("%s: %s: got %v, want %v", testLabel(cfg), c.name, err, c.want)
+            }
+            if getDefault() != initial { t.Errorf("%s: %s: the default octree was replaced", testLabel(cfg), c.name) }
+        }
+    }
+} //end func TestCancel
+
+func TestConvert(t *testing.T) {
+    //JSON files must convert to binary and back to the very same bytes, as must binary files, with or without points.
+    var(
+        dir    = t.TempDir()
+        points = testPoints(2000, 4)
+    )
+    read := func(file string) []byte {
+        data, err := os.ReadFile(file)
+        if err != nil { t.Fatal(err) }
+        return data
+    }
+    for _, cfg := range testConfigs() {
+        tree := testTree(t, cfg, points)
+        for _, compact := range []bool{ false, true } {
+            for _, coords := range []bool{ false, true } {
+                var(
+                    label   = fmt.Sprintf("%s, compact %v, points %v", testLabel(cfg), compact, coords)
+                    json1   = filepath.Join(dir, "1.json")
+                    json2   = filepath.Join(dir, "2.json")
+                    binary1 = filepath.Join(dir, "1.octb")
+                    binary2 = filepath.Join(dir, "2.octb")
+                )
+                if err := tree.Export(json1, compact, coords); err != nil { t.Fatal(err) }
+                if err := tree.ExportBinary(binary1, coords); err != nil { t.Fatal(err) }
+                if err := Convert(json1, binary2, compact); err != nil { t.Fatal(err) }
+                if err := Convert(binary2, json2, compact); err != nil { t.Fatal(err) }
+                if !bytes.Equal(read(json1), read(json2)) { t.Errorf("%s: JSON->binary->JSON changed the file", label) }
+                if !bytes.Equal(read(binary1), read(binary2)) { t.Errorf("%s: JSON->binary differs from the export", label) }
+                if err := Convert(binary1, json2, compact); err != nil { t.Fatal(err) }
+                if err := Convert(json2, binary2, compact); err != nil { t.Fatal(err) }
+                if !bytes.Equal(read(binary1), read(binary2)) { t.Errorf("%s: binary->JSON->binary changed the file", label) }
+            }
+        }
+        var(
+            data     bytes.Buffer
+            imported Octree
+        )
+        if _, err := tree.WriteJSON(&data, true, true); err != nil { t.Fatal(err) }
+        if _, err := imported.ReadFrom(&data); err != nil { t.Fatal(err) }
+        if restored, err := imported.Points(); err != nil || !reflect.DeepEqual(restored, points) {
+            t.Errorf("%s: the data set wasn't restored from the embedded points: %v", testLabel(cfg), err)
+        }
+    }
+
+    var(
+        data     bytes.Buffer
+        imported Octree
+        long     = DataSet{ strings.Repeat("k", 3*_binaryKeyChunk + 1): { 0.5, 0.5, 0.5 } } //key read by several chunks
+    )
+    if _, err := testTree(t, testConfigs()[0], long).WriteBinary(&data, true); err != nil { t.Fatal(err) }
+    if _, err := imported.ReadBinary(&data); err != nil { t.Fatal(err) }
+    if restored, err := imported.Points(); err != nil || !reflect.DeepEqual(restored, long) {
+        t.Errorf("the long key wasn't restored: %v", err)
+    }
+} //end func TestConvert
+
+func TestImportRejects(t *testing.T) {
+    //Truncated, out-of-range and cyclic files, and points outside their leaf's octant, must be rejected with ErrBadFile,
+    //the octree being left unchanged.
+    var(
+        binaryData bytes.Buffer
+        jsonData   bytes.Buffer
+        tree       = testTree(t, Config{ METHOD: "Centroid", TERMINAL_N: 8 }, testPoints(200, 5))
+    )
+    if _, err := tree.WriteBinary(&binaryData, false); err != nil { t.Fatal(err) }
+    if _, err := tree.WriteJSON(&jsonData, true, false); err != nil { t.Fatal(err) }
+    if tree.nodes[0].LEAF || tree.nodes[0].CHILDREN[0] != 1 { t.Fatal("unexpected root node") }
+    firstLink := 128 + len(tree.stats.HOW) + 40                           //offsets in the binary format of the root's
+    keyTable  := 128 + len(tree.stats.HOW) + _binaryNodeLen*len(tree.nodes) //first child link & of the key table
+
+    patchBinary := func(link uint32) []byte {
+        data := append([]byte(nil), binaryData.Bytes()...)
+        for i := 0; i < 4; i++ { data[firstLink + i] = byte(link >> (8*i)) }
+        return data
+    }
+    patchJSON := func(link string) []byte {
+        data := bytes.Replace(jsonData.Bytes(), []byte(`"children":[1,`), []byte(`"children":[` + link + `,`), 1)
+        if bytes.Equal(data, jsonData.Bytes()) { t.Fatal("the root's children weren't found") }
+        return data
+    }
+    binaryCases := map[string][]byte{
+        "truncated header":  binaryData.Bytes()[:100],
+        "truncated nodes":   binaryData.Bytes()[:firstLink + 100],
+        "truncated keys":    binaryData.Bytes()[:binaryData.Len() - 1],
+        "out-of-range link": patchBinary(1 << 30),
+        "negative link":     patchBinary(math.MaxUint32 - 1),
+        "cyclic link":       patchBinary(0),
+        "huge key length":   append(binaryData.Bytes()[:keyTable:keyTable], 0xff, 0xff, 0xff, 0xff, 0x07, 'k'),
+    }
+    var(
+        leaves []int  //non-empty leaf nodes
+        moved  bytes.Buffer
+        nodes  = tree.nodes
+    )
+    for k := range nodes {
+        if nodes[k].LEAF && nodes[k].N > 0 { leaves = append(leaves, k) }
+    }
+    tree.nodes = append([]node(nil), nodes...) //a point of the first leaf moved to the last one's
+    tree.nodes[leaves[0]].PTS = append([]DataCoords{ nodes[leaves[len(leaves)-1]].PTS[0] }, nodes[leaves[0]].PTS[1:]...)
+    if _, err := tree.WriteJSON(&moved, true, true); err != nil { t.Fatal(err) }
+    tree.nodes = nodes
+    jsonCases := map[string][]byte{
+        "truncated":         jsonData.Bytes()[:jsonData.Len() / 2],
+        "out-of-range link": patchJSON("100000"),
+        "cyclic link":       patchJSON("0"),
+        "misplaced point":   moved.Bytes(),
+    }
+    want := fmt.Sprint(tree.nodes)
+    for name, data := range binaryCases {
+        var before, after runtime.MemStats
+        runtime.ReadMemStats(&before)
+        if _, err := tree.ReadBinary(bytes.NewReader(data)); !errors.Is(err, ErrBadFile) {
+            t.Errorf("binary, %s: got %v, want ErrBadFile", name, err)
+        }
+        runtime.ReadMemStats(&after)
+        if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1 << 24 {
+            t.Errorf("binary, %s: %d bytes allocated", name, allocated)
+        }
+        if fmt.Sprint(tree.nodes) != want { t.Fatalf("binary, %s: the octree was changed", name) }
+    }
+    for name, data := range jsonCases {
+        if _, err := tree.ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrBadFile) {
+            t.Errorf("JSON, %s: got %v, want ErrBadFile", name, err)
+        }
+        if fmt.Sprint(tree.nodes) != want { t.Fatalf("JSON, %s: the octree was changed", name) }
+    }
+} //end func TestImportRejects
+
+func TestProgress(t *testing.T) {
+    //The callback must see every point placed, one call at a time, and must not be retained by the octree.
+    var(
+        calls  int
+        last   Progress
+        points = testPoints(3*_parallelGrain, 6)
+    )
+    for _, cfg := range testConfigs() {
+        calls, last  = 0, Progress{}
+        cfg.PROGRESS = func(p Progress) { calls++; last = p }
+        tree := testTree(t, cfg, points)
+        if last.POINTS != len(points) || last.TOTAL != len(points) || last.NODES != len(tree.nodes) || calls != last.NODES {
+            t.Errorf("%s: last progress %+v after %d calls for %d nodes", testLabel(cfg), last, calls, len(tree.nodes))
+        }
+        if tree.Config().PROGRESS != nil { t.Errorf("%s: the callback was retained", testLabel(cfg)) }
+    }
+} //end func TestProgress